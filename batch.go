@@ -0,0 +1,157 @@
+package curly
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// newState creates the state of an execution with the given formatters, followed by the formatters of
+// the engine.
+func newState(ctx context.Context, e *Engine, formatters []Formatter) *state {
+	s := &state{
+		ctx:        ctx,
+		engine:     e,
+		formatters: snapshot(e.formattersFor(formatters)),
+		values:     make(map[valueKey]lookupResult),
+	}
+	s.shared = len(s.formatters)
+	return s
+}
+
+// valueKey identifies a value read during an execution by the index of its formatter among the
+// formatters given to the execution, and its identifier.
+type valueKey struct {
+	index      int
+	identifier string
+}
+
+// lookupResult is a value read during an execution, or the error of the formatter.
+type lookupResult struct {
+	value any
+	err   error
+}
+
+// prefetch reads ahead of rendering the values of the identifiers the nodes use: at once from each
+// BatchFormatter, and concurrently from the other formatters when the engine allows parallel lookups.
+// The errors are kept for the placeholders to report them.
+func (s *state) prefetch(nodes []node) {
+	batch := false
+	for _, f := range s.formatters {
+		if _, ok := asBatch(f); ok {
+			batch = true
+		}
+	}
+	if (!batch && s.engine.parallel < 2) || s.ctx.Err() != nil {
+		return
+	}
+
+	// Group the identifiers by formatter
+	groups := map[int][]string{}
+	var order []int
+	seen := map[string]bool{}
+	for _, identifier := range usedIdentifiers(nodes, nil) {
+		if seen[identifier] {
+			continue
+		}
+		seen[identifier] = true
+		for i, f := range s.formatters {
+			if !f.Valid(identifier) {
+				continue
+			}
+
+			// A stream keeps the values read for its earlier parts
+			if _, ok := s.values[valueKey{index: i, identifier: identifier}]; ok {
+				break
+			}
+			_, batch := asBatch(f)
+			if _, ok := asModifier(f, identifier); !ok && (batch || s.engine.parallel > 1) {
+				if groups[i] == nil {
+					order = append(order, i)
+				}
+				groups[i] = append(groups[i], identifier)
+			}
+			break
+		}
+	}
+
+	var keys []valueKey
+	for _, i := range order {
+		f, ok := asBatch(s.formatters[i])
+		if !ok {
+			for _, identifier := range groups[i] {
+				keys = append(keys, valueKey{index: i, identifier: identifier})
+			}
+			continue
+		}
+
+		// The identifiers left out of the batch are read one by one
+		values, err := f.Values(groups[i])
+		for _, identifier := range groups[i] {
+			key := valueKey{index: i, identifier: identifier}
+			if err != nil {
+				s.values[key] = lookupResult{err: err}
+			} else if v, ok := values[identifier]; ok {
+				s.values[key] = lookupResult{value: v}
+			}
+		}
+	}
+
+	results := make([]lookupResult, len(keys))
+	slots := make(chan struct{}, max(s.engine.parallel, 1))
+	var wg sync.WaitGroup
+	for j, key := range keys {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			results[j].value, results[j].err = s.value(s.formatters[key.index], key.identifier)
+			<-slots
+		}()
+	}
+	wg.Wait()
+	for j, key := range keys {
+		s.values[key] = results[j]
+	}
+}
+
+// usedIdentifiers returns the identifiers whose values the nodes need, except those provided by the
+// elements of a loop or by the parameters of a partial, listed in shadowed. The fallbacks are left out,
+// as they are only read when the identifiers before them are missing.
+func usedIdentifiers(nodes []node, shadowed []string) []string {
+	var identifiers []string
+	add := func(identifier string) {
+		if identifier == "" {
+			return
+		}
+		for _, name := range shadowed {
+			if strings.EqualFold(identifier, name) || strings.HasPrefix(strings.ToLower(identifier), strings.ToLower(name)+".") {
+				return
+			}
+		}
+		identifiers = append(identifiers, identifier)
+	}
+
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *fieldNode:
+			add(n.identifier)
+		case *ifNode:
+			add(n.cond.left)
+			add(n.cond.right.identifier)
+			identifiers = append(identifiers, usedIdentifiers(n.then, shadowed)...)
+			identifiers = append(identifiers, usedIdentifiers(n.otherwise, shadowed)...)
+		case *eachNode:
+			add(n.identifier)
+			identifiers = append(identifiers, usedIdentifiers(n.otherwise, shadowed)...)
+		case *partialNode:
+			names := shadowed[:len(shadowed):len(shadowed)]
+			for _, prm := range n.params {
+				add(prm.identifier)
+				names = append(names, prm.name)
+			}
+			identifiers = append(identifiers, usedIdentifiers(n.nodes, names)...)
+		}
+	}
+	return identifiers
+}
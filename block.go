@@ -0,0 +1,560 @@
+package curly
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// blockTag is a tag opening, dividing or closing a section, such as {#if ready}, {#else} or {/if}.
+type blockTag struct {
+	name   string // lowercase name with its "#" or "/" sign
+	args   string // trimmed text following the name
+	raw    string // source text of the tag
+	offset int    // byte offset of the tag in the template
+	start  int    // start of the source of the tag, including the line it stands alone on
+	end    int    // end of the source of the tag, including the line it stands alone on
+}
+
+// opening reports whether the tag opens a section, rather than dividing or closing one.
+func (t *blockTag) opening() bool {
+	return strings.HasPrefix(t.name, "#") && t.name != "#else"
+}
+
+// scanTag reads a tag such as {#if status == "SUCCESS"} or {/if} at the start of s and returns it with
+// its length. A closing brace between quotes or after a backslash does not end the tag.
+func scanTag(s string) (*blockTag, int, bool) {
+	if len(s) == 0 || s[0] != '{' {
+		return nil, 0, false
+	}
+	i := skipSpace(s, 1)
+	if i >= len(s) || (s[i] != '#' && s[i] != '/') {
+		return nil, 0, false
+	}
+	start := i
+	i++
+	for i < len(s) && isLetter(s[i]) {
+		i++
+	}
+	if i == start+1 {
+		return nil, 0, false
+	}
+
+	var quote byte
+	for j := i; j < len(s); j++ {
+		switch c := s[j]; {
+		case c == '\\' && j+1 < len(s):
+			j++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '}':
+			return &blockTag{
+				name: strings.ToLower(s[start:i]),
+				args: strings.TrimSpace(s[i:j]),
+			}, j + 1, true
+		}
+	}
+	return nil, 0, false
+}
+
+// standalone reports whether the tag spanning text[start:end] stands alone on its line, and returns the
+// extent of that line including its line break.
+func standalone(text string, start int, end int) (int, int, bool) {
+	for start > 0 && (text[start-1] == ' ' || text[start-1] == '\t') {
+		start--
+	}
+	if start > 0 && text[start-1] != '\n' {
+		return 0, 0, false
+	}
+	for end < len(text) && (text[end] == ' ' || text[end] == '\t' || text[end] == '\r') {
+		end++
+	}
+	if end < len(text) {
+		if text[end] != '\n' {
+			return 0, 0, false
+		}
+		end++
+	}
+	return start, end, true
+}
+
+// section is a node holding other nodes, such as the branches of a conditional.
+type section interface {
+	node
+	branches() [][]node
+}
+
+// parseSection reads the section opened by the tag up to its closing tag.
+func (p *templateParser) parseSection(tag *blockTag) (node, error) {
+	switch tag.name {
+	case "#if":
+		return p.parseIf(tag)
+	case "#each":
+		return p.parseEach(tag)
+	}
+	return nil, p.fail(tag, errors.New("unknown section"))
+}
+
+// parseIf reads a conditional section {#if condition}...{#else}...{/if}.
+func (p *templateParser) parseIf(tag *blockTag) (node, error) {
+	cond, ok := parseCondition(tag.args)
+	if !ok {
+		return nil, p.fail(tag, errors.New("invalid condition"))
+	}
+	n := &ifNode{raw: tag.raw, offset: tag.offset, cond: cond}
+
+	var end *blockTag
+	var err error
+	n.then, end, err = p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil && end.name == "#else" && end.args == "" {
+		n.otherwise, end, err = p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if end == nil {
+		return nil, p.fail(tag, errors.New("missing {/if}"))
+	}
+	if end.name != "/if" || end.args != "" {
+		return nil, p.fail(end, fmt.Errorf("unexpected %s", end.raw))
+	}
+	n.source = p.text[tag.start:end.end]
+	return n, nil
+}
+
+// ifNode is a conditional section {#if condition}...{#else}...{/if}.
+type ifNode struct {
+	raw       string
+	source    string
+	offset    int
+	cond      condition
+	then      []node
+	otherwise []node
+}
+
+func (n *ifNode) branches() [][]node {
+	return [][]node{n.then, n.otherwise}
+}
+
+func (n *ifNode) render(w io.StringWriter, s *state) error {
+	ok, missing, err := n.cond.eval(s)
+	if err != nil {
+		e := s.fail(errorKind(err, ErrInvalidValue), n.offset, n.raw, err)
+		e.Identifier = n.cond.left
+		return e
+	}
+
+	// A later stage may provide the identifiers the condition needs
+	if len(missing) != 0 && s.engine.missing == MissingKeep {
+		for _, identifier := range missing {
+			s.unresolve(identifier)
+		}
+		_, err = w.WriteString(n.source)
+		return err
+	}
+
+	branch := n.then
+	if !ok {
+		branch = n.otherwise
+	}
+	for _, c := range branch {
+		if err := c.render(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fields describes the identifiers of the condition located in the source text of the template.
+func (n *ifNode) fields(text string) []Field {
+	line, column := position(text, n.offset)
+	fields := []Field{{Identifier: n.cond.left, Raw: n.raw, Offset: n.offset, Line: line, Column: column}}
+	if n.cond.right.identifier != "" {
+		fields = append(fields, Field{Identifier: n.cond.right.identifier, Raw: n.raw, Offset: n.offset, Line: line, Column: column})
+	}
+	return fields
+}
+
+// parseEach reads a loop section {#each identifier}...{#else}...{/each}.
+func (p *templateParser) parseEach(tag *blockTag) (node, error) {
+	if tag.args == "" || scanIdentifier(tag.args, 0) != len(tag.args) {
+		return nil, p.fail(tag, errors.New("invalid identifier"))
+	}
+	n := &eachNode{raw: tag.raw, offset: tag.offset, identifier: tag.args}
+
+	var end *blockTag
+	var err error
+	n.body, end, err = p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil && end.name == "#else" && end.args == "" {
+		n.otherwise, end, err = p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if end == nil {
+		return nil, p.fail(tag, errors.New("missing {/each}"))
+	}
+	if end.name != "/each" || end.args != "" {
+		return nil, p.fail(end, fmt.Errorf("unexpected %s", end.raw))
+	}
+	n.source = p.text[tag.start:end.end]
+	return n, nil
+}
+
+// eachNode is a loop section {#each identifier}...{#else}...{/each}, rendering its body for every
+// element of a slice, an array or a map, and the optional else section when there is none.
+type eachNode struct {
+	raw        string
+	source     string
+	offset     int
+	identifier string
+	body       []node
+	otherwise  []node
+}
+
+func (n *eachNode) branches() [][]node {
+	return [][]node{n.body, n.otherwise}
+}
+
+func (n *eachNode) render(w io.StringWriter, s *state) error {
+	value, _, found, err := s.lookup(n.identifier, "")
+	if err != nil {
+		return n.fail(s, errorKind(err, ErrInvalidValue), err)
+	}
+
+	if !found && s.engine.missing == MissingError {
+		return n.fail(s, ErrUnknownIdentifier, nil)
+	}
+	if !found {
+		s.unresolve(n.identifier)
+	}
+
+	// A later stage may provide the elements
+	if !found && s.engine.missing == MissingKeep {
+		_, err = w.WriteString(n.source)
+		return err
+	}
+
+	items, ok := elements(value)
+	if !ok {
+		return n.fail(s, ErrInvalidValue, fmt.Errorf("cannot iterate over %T", value))
+	}
+	if len(items) == 0 {
+		for _, c := range n.otherwise {
+			if err := c.render(w, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The helpers and the fields of the element take precedence over the formatters of the template
+	inner := *s
+	for i, item := range items {
+		inner.formatters = append([]Formatter{&loopFormatter{item: item, index: i, count: len(items)}}, s.formatters...)
+		for _, c := range n.body {
+			if err := c.render(w, &inner); err != nil {
+				return err
+			}
+		}
+	}
+	s.unresolved = inner.unresolved
+	return nil
+}
+
+// fail returns a TemplateError of the given kind located at the tag opening the loop.
+func (n *eachNode) fail(s *state, kind error, err error) error {
+	e := s.fail(kind, n.offset, n.raw, err)
+	e.Identifier = n.identifier
+	return e
+}
+
+// elements returns the elements of a slice or an array, or the entries of a map ordered by key.
+// A nil value has no elements.
+func elements(value any) ([]loopItem, bool) {
+	v := indirect(reflect.ValueOf(value))
+	if !v.IsValid() {
+		return nil, true
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]loopItem, v.Len())
+		for i := range items {
+			items[i] = loopItem{value: v.Index(i)}
+		}
+		return items, true
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprintf("%v", keys[i].Interface()) < fmt.Sprintf("%v", keys[j].Interface())
+		})
+		items := make([]loopItem, len(keys))
+		for i, key := range keys {
+			items[i] = loopItem{key: key.Interface(), value: v.MapIndex(key)}
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+// loopItem is an element of a loop, with its key when the loop iterates over a map.
+type loopItem struct {
+	key   any
+	value reflect.Value
+}
+
+// loopFormatter provides the helpers of a loop and the fields of the current element.
+//
+//	index       position of the element, starting at 0
+//	first, last whether the element is the first or the last one
+//	key         key of the element in a map
+//	item        the element itself, item.name selecting one of its fields
+type loopFormatter struct {
+	item  loopItem
+	index int
+	count int
+}
+
+// Valid checks if the identifier is a helper of the loop or a field of the element.
+func (f *loopFormatter) Valid(identifier string) bool {
+	_, err := f.Value(identifier)
+	return err == nil
+}
+
+// Value returns the value of the helper or the field of the element.
+func (f *loopFormatter) Value(identifier string) (any, error) {
+	switch strings.ToLower(identifier) {
+	case "index":
+		return f.index, nil
+	case "first":
+		return f.index == 0, nil
+	case "last":
+		return f.index == f.count-1, nil
+	case "key":
+		if f.item.key != nil {
+			return f.item.key, nil
+		}
+	case "item":
+		return valueOf(f.item.value), nil
+	}
+
+	path := identifier
+	if len(path) > 5 && strings.EqualFold(path[:5], "item.") {
+		path = path[5:]
+	}
+	v, missing, keys, ok := resolvePath(f.item.value, path, true)
+	if ok && keys != nil {
+		return nil, ambiguityError(identifier, keys)
+	}
+	if !ok {
+		return nil, pathError(strings.ToLower(identifier), missing)
+	}
+	return valueOf(v), nil
+}
+
+// anyFormatter accepts every identifier. It stands for the fields of the elements of a loop, which are
+// only known when the template is executed.
+type anyFormatter struct{}
+
+func (anyFormatter) Valid(identifier string) bool {
+	return true
+}
+
+func (anyFormatter) Value(identifier string) (any, error) {
+	return nil, nil
+}
+
+// condition is the condition of a section: an identifier, optionally negated, or the comparison of an
+// identifier with a literal or another identifier.
+type condition struct {
+	negate bool
+	left   string
+	op     string
+	right  operand
+}
+
+// operand is the right-hand side of a comparison, either an identifier or a literal.
+type operand struct {
+	identifier string
+	literal    string
+}
+
+// conditionOperators lists the comparison operators, the longest first.
+var conditionOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseCondition reads a condition such as pln.ppj, !info, status == "SUCCESS" or amount >= 100000.
+// Texts are quoted, numbers may be written as is and any other operand is an identifier.
+func parseCondition(args string) (condition, bool) {
+	var c condition
+	if strings.HasPrefix(args, "!") && !strings.HasPrefix(args, "!=") {
+		c.negate = true
+		args = strings.TrimSpace(args[1:])
+	}
+	end := scanIdentifier(args, 0)
+	if end == 0 {
+		return c, false
+	}
+	c.left = args[:end]
+	args = strings.TrimSpace(args[end:])
+	if args == "" {
+		return c, true
+	}
+	if c.negate {
+		return c, false
+	}
+
+	for _, op := range conditionOperators {
+		if strings.HasPrefix(args, op) {
+			c.op = op
+			args = strings.TrimSpace(args[len(op):])
+			break
+		}
+	}
+	if c.op == "" || args == "" {
+		return c, false
+	}
+
+	switch {
+	case args[0] == '"' || args[0] == '\'':
+		var sb strings.Builder
+		for i := 1; i < len(args); i++ {
+			switch ch := args[i]; {
+			case ch == '\\' && i+1 < len(args):
+				sb.WriteByte(args[i+1])
+				i++
+			case ch == args[0]:
+				if i != len(args)-1 {
+					return c, false
+				}
+				c.right.literal = sb.String()
+				return c, true
+			default:
+				sb.WriteByte(ch)
+			}
+		}
+		return c, false
+	case isLetter(args[0]):
+		if scanIdentifier(args, 0) != len(args) {
+			return c, false
+		}
+		c.right.identifier = args
+	default:
+		if _, ok := numeric(args); !ok {
+			return c, false
+		}
+		c.right.literal = args
+	}
+	return c, true
+}
+
+// eval evaluates the condition and returns the identifiers no formatter provides, which count as nil.
+func (c *condition) eval(s *state) (bool, []string, error) {
+	var missing []string
+	left, _, found, err := s.lookup(c.left, "")
+	if err != nil {
+		return false, nil, err
+	}
+	if !found {
+		missing = append(missing, c.left)
+	}
+	if c.op == "" {
+		return truthy(left) != c.negate, missing, nil
+	}
+
+	var right any = c.right.literal
+	if c.right.identifier != "" {
+		right, _, found, err = s.lookup(c.right.identifier, "")
+		if err != nil {
+			return false, nil, err
+		}
+		if !found {
+			missing = append(missing, c.right.identifier)
+		}
+	}
+	return compare(left, right, c.op), missing, nil
+}
+
+// truthy reports whether the value selects the first branch of a section. Nil and empty values,
+// false and numbers equal to zero are false.
+func truthy(value any) bool {
+	if isEmpty(value) {
+		return false
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return !v.IsZero()
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len() != 0
+	}
+	return true
+}
+
+// compare compares the values with the operator, as numbers when both are numbers and as text
+// otherwise. A nil value only equals another nil value.
+func compare(left any, right any, op string) bool {
+	if left == nil || right == nil {
+		switch op {
+		case "==":
+			return left == nil && right == nil
+		case "!=":
+			return left != nil || right != nil
+		}
+		return false
+	}
+
+	l, r := fmt.Sprintf("%v", left), fmt.Sprintf("%v", right)
+	cmp := strings.Compare(l, r)
+	if x, ok := numeric(l); ok {
+		if y, ok := numeric(r); ok {
+			cmp = 0
+			if x < y {
+				cmp = -1
+			} else if x > y {
+				cmp = 1
+			}
+		}
+	}
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// numeric converts the text to a finite number.
+func numeric(text string) (float64, bool) {
+	n, err := numberOf(text)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false
+	}
+	return n, true
+}
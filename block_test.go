@@ -0,0 +1,173 @@
+package curly_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ceebydith/curly"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditional(t *testing.T) {
+	testConditional := templateTester{
+		formatters: []curly.Formatter{curly.NewMapFormatter(map[string]any{
+			"status":  "SUCCESS",
+			"info":    "",
+			"ppj":     1819,
+			"stamp":   0,
+			"paid":    true,
+			"amount":  150000,
+			"limit":   100000,
+			"items":   []any{},
+			"name":    "John Doe",
+			"closing": "}",
+		})},
+		scenarios: []templateScenarioTest{
+			{"{#if ppj}PPJ: {ppj}{/if}", "PPJ: 1819", nil},
+			{"{#if stamp}Stamp: {stamp}{/if}", "", nil},
+			{"{#if info}Info: {info}{#else}No info{/if}", "No info", nil},
+			{"{#if !info}No info{/if}", "No info", nil},
+			{"{#if nickname}{nickname}{#else}{name}{/if}", "John Doe", nil},
+			{"{#if items}items{#else}empty{/if}", "empty", nil},
+			{"{ #IF paid }paid{/If}", "paid", nil},
+			{"{#if status == \"SUCCESS\"}OK{#else}FAILED{/if}", "OK", nil},
+			{"{#if status != 'SUCCESS'}FAILED{#else}OK{/if}", "OK", nil},
+			{"{#if status == \"success\"}OK{#else}FAILED{/if}", "FAILED", nil},
+			{"{#if amount >= 100000}big{/if}", "big", nil},
+			{"{#if amount > limit}over{/if}", "over", nil},
+			{"{#if amount < 99999.5}small{#else}large{/if}", "large", nil},
+			{"{#if nickname == \"\"}{#else}set{/if}", "set", nil},
+			{"{#if nickname != \"\"}set{/if}", "set", nil},
+			{"{#if closing == \"}\"}closing{/if}", "closing", nil},
+			{"{#if closing == \"\\}\"}closing{/if}", "closing", nil},
+			{"{#if ppj}{#if status == \"SUCCESS\"}both{#else}ppj{/if}{#else}none{/if}", "both", nil},
+			{"\\{#if ppj\\}{ppj}\\{/if\\}", "{#if ppj}1819{/if}", nil},
+			{"Nama: {name}\n{#if ppj}\nPPJ : {ppj}\n{/if}\n  {#if stamp}\nMaterai: {stamp}\n  {#else}\nTanpa materai\n  {/if}\nTotal: {amount}",
+				"Nama: John Doe\nPPJ : 1819\nTanpa materai\nTotal: 150000", nil},
+			{"{#if ppj}PPJ", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{#if ppj}", Offset: 0, Line: 1, Column: 1, Err: errors.New("missing {/if}"),
+			}},
+			{"PPJ{/if}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{/if}", Offset: 3, Line: 1, Column: 4, Err: errors.New("unexpected {/if}"),
+			}},
+			{"{#if ppj}a{#else}b{#else}c{/if}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{#else}", Offset: 18, Line: 1, Column: 19, Err: errors.New("unexpected {#else}"),
+			}},
+			{"{#if ppj ==}a{/if}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{#if ppj ==}", Offset: 0, Line: 1, Column: 1, Err: errors.New("invalid condition"),
+			}},
+			{"{#if status == SUCCESS OK}a{/if}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{#if status == SUCCESS OK}", Offset: 0, Line: 1, Column: 1, Err: errors.New("invalid condition"),
+			}},
+			{"{#when ppj}a{/when}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{#when ppj}", Offset: 0, Line: 1, Column: 1, Err: errors.New("unknown section"),
+			}},
+			{"{#if ppj}{unknown}{/if}", "", &curly.TemplateError{
+				Kind: curly.ErrUnknownIdentifier, Text: "{unknown}", Offset: 9, Line: 1, Column: 10, Identifier: "unknown",
+			}},
+			{"{#if stamp}{unknown}{/if}", "", nil},
+		},
+	}
+
+	t.Run("TestConditional", testConditional.Test)
+}
+
+func TestConditionalStaged(t *testing.T) {
+	text := "Status: {status}\n{#if ppj > 0}\nPPJ: {ppj}\n{/if}\n{#if name}Dear {name}{/if}"
+
+	staged := curly.NewEngine(curly.WithMissing(curly.MissingKeep))
+	partial, unresolved, err := staged.FormatUnresolved(text, curly.NewMapFormatter(map[string]any{"status": "SUCCESS", "name": "John"}))
+	require.NoError(t, err)
+	require.Equal(t, "Status: SUCCESS\n{#if ppj > 0}\nPPJ: {ppj}\n{/if}\nDear John", partial)
+	require.Equal(t, []string{"ppj"}, unresolved)
+
+	final, err := curly.Format(partial, curly.NewMapFormatter(map[string]any{"ppj": 1819}))
+	require.NoError(t, err)
+	require.Equal(t, "Status: SUCCESS\nPPJ: 1819\nDear John", final)
+
+	fields, err := curly.Identifiers(text)
+	require.NoError(t, err)
+	var identifiers []string
+	for _, f := range fields {
+		identifiers = append(identifiers, f.Identifier)
+	}
+	require.Equal(t, "status ppj ppj name name", strings.Join(identifiers, " "))
+
+	require.ErrorIs(t, curly.Validate(text, curly.NewMapFormatter(map[string]any{"status": "", "ppj": 0})), curly.ErrUnknownIdentifier)
+	require.NoError(t, curly.Validate(text, curly.NewMapFormatter(map[string]any{"status": "", "ppj": 0, "name": ""})))
+}
+
+func TestLoop(t *testing.T) {
+	type product struct {
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
+	}
+
+	testLoop := templateTester{
+		formatters: []curly.Formatter{curly.NewMapFormatter(map[string]any{
+			"store": "TOKO ABC",
+			"items": []any{
+				map[string]any{"name": "Token PLN 20K", "price": 20000},
+				map[string]any{"name": "Admin", "price": 2500},
+			},
+			"products": []product{{"Pulsa 10K", 10500}, {"Pulsa 25K", 25250}},
+			"members":  []string{"John", "Jane", "Joe"},
+			"fees":     map[string]int{"b.admin": 2500, "a.ppj": 1819},
+			"empty":    []any{},
+			"nothing":  nil,
+			"name":     "Customer",
+		})},
+		scenarios: []templateScenarioTest{
+			{"{#each items}{name|left(15)}{price|money(,)|right(8)}\n{/each}", "Token PLN 20K    20.000\nAdmin             2.500\n", nil},
+			{"{#each products}{index+1}. {name} {price|money(,2)}\n{/each}", "1. Pulsa 10K 10.500,00\n2. Pulsa 25K 25.250,00\n", nil},
+			{"{#each members}{item}{#if !last}, {/if}{/each}", "John, Jane, Joe", nil},
+			{"{#each members}{#if first}[{/if}{item|pre(#)}{#if last}]{/if}{/each}", "[#John#Jane#Joe]", nil},
+			{"{#each fees}{key}={item} {/each}", "a.ppj=1819 b.admin=2500 ", nil},
+			{"{#each items}{store}: {item.name}; {/each}", "TOKO ABC: Token PLN 20K; TOKO ABC: Admin; ", nil},
+			{"{#each members}{name} {/each}", "Customer Customer Customer ", nil},
+			{"{#each empty}x{#else}no items{/each}", "no items", nil},
+			{"{#each nothing}x{#else}no items{/each}", "no items", nil},
+			{"{#each items}{#each members}{index}{/each}|{/each}", "012|012|", nil},
+			{"{#each items}{#if price > 10000}{name}{/if}{/each}", "Token PLN 20K", nil},
+			{"Struk {store}\n{#each items}\n- {name}\n{/each}\nTerima kasih", "Struk TOKO ABC\n- Token PLN 20K\n- Admin\nTerima kasih", nil},
+			{"{#each store}x{/each}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidValue, Text: "{#each store}", Offset: 0, Line: 1, Column: 1, Identifier: "store",
+				Err: errors.New("cannot iterate over string"),
+			}},
+			{"{#each itmes}x{#else}none{/each}", "", &curly.TemplateError{
+				Kind: curly.ErrUnknownIdentifier, Text: "{#each itmes}", Offset: 0, Line: 1, Column: 1, Identifier: "itmes",
+			}},
+			{"{#each items}{code}{/each}", "", &curly.TemplateError{
+				Kind: curly.ErrUnknownIdentifier, Text: "{code}", Offset: 13, Line: 1, Column: 14, Identifier: "code",
+			}},
+			{"{#each items}x", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{#each items}", Offset: 0, Line: 1, Column: 1, Err: errors.New("missing {/each}"),
+			}},
+			{"{#each items}x{/if}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{/if}", Offset: 14, Line: 1, Column: 15, Err: errors.New("unexpected {/if}"),
+			}},
+			{"{#each items == 1}x{/each}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{#each items == 1}", Offset: 0, Line: 1, Column: 1, Err: errors.New("invalid identifier"),
+			}},
+		},
+	}
+
+	t.Run("TestLoop", testLoop.Test)
+
+	data := curly.NewMapFormatter(map[string]any{"items": []any{}})
+	require.NoError(t, curly.Validate("{#each items}{name|left(20)}{price|money(,)|right(12)}{/each}", data))
+	require.ErrorIs(t, curly.Validate("{#each items}{name|upper()}{/each}", data), curly.ErrInvalidModifier)
+	require.NoError(t, curly.Validate("{#each items}{#each members}{item}{/each}{/each}", data))
+
+	err := curly.Validate("{#each itmes}x{#else}none{/each}", data)
+	require.ErrorIs(t, err, curly.ErrUnknownIdentifier)
+	require.EqualError(t, err, "unknown identifier: \"{#each itmes}\" at line 1, column 1")
+
+	// An unknown loop renders its else section and is reported when missing values are allowed
+	lenient := curly.NewEngine(curly.WithMissing(curly.MissingEmpty))
+	text, unresolved, err := lenient.FormatUnresolved("{#each itmes}x{#else}none{/each}", data)
+	require.NoError(t, err)
+	require.Equal(t, "none", text)
+	require.Equal(t, []string{"itmes"}, unresolved)
+}
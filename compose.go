@@ -0,0 +1,327 @@
+package curly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// NewFuncFormatter creates a new FuncFormatter providing the identifiers accepted by valid, whose values
+// are returned by value.
+func NewFuncFormatter(valid func(identifier string) bool, value func(identifier string) (any, error)) *FuncFormatter {
+	return &FuncFormatter{
+		valid: valid,
+		value: value,
+	}
+}
+
+// FuncFormatter adapts a function to a Formatter, for small data sources that do not deserve a type of
+// their own. Only Value calls the function, so that validating a template reads no value.
+type FuncFormatter struct {
+	valid func(identifier string) bool
+	value func(identifier string) (any, error)
+}
+
+// Valid checks if the identifier is accepted by the formatter.
+func (f *FuncFormatter) Valid(identifier string) bool {
+	return f.valid(identifier)
+}
+
+// Value returns the value of the identifier returned by the function.
+func (f *FuncFormatter) Value(identifier string) (any, error) {
+	return f.value(identifier)
+}
+
+// NewPrefixFormatter creates a new PrefixFormatter providing the identifiers of the formatter under the
+// given prefix, such as "pln.".
+func NewPrefixFormatter(prefix string, formatter Formatter) *PrefixFormatter {
+	return &PrefixFormatter{
+		prefix:    prefix,
+		formatter: formatter,
+	}
+}
+
+// PrefixFormatter scopes a formatter under a namespace: the identifier pln.nama is read as nama from the
+// formatter. The prefix is matched case-insensitively. The date arithmetic, snapshots, context and
+// batches of the formatter are kept.
+type PrefixFormatter struct {
+	prefix    string
+	formatter Formatter
+}
+
+// strip removes the prefix from the identifier.
+func (f *PrefixFormatter) strip(identifier string) (string, bool) {
+	if len(identifier) <= len(f.prefix) || !strings.EqualFold(identifier[:len(f.prefix)], f.prefix) {
+		return "", false
+	}
+	return identifier[len(f.prefix):], true
+}
+
+// Valid checks if the identifier starts with the prefix and the formatter provides the rest.
+func (f *PrefixFormatter) Valid(identifier string) bool {
+	name, ok := f.strip(identifier)
+	return ok && f.formatter.Valid(name)
+}
+
+// Value returns the value of the identifier without its prefix.
+func (f *PrefixFormatter) Value(identifier string) (any, error) {
+	name, ok := f.strip(identifier)
+	if !ok {
+		return nil, fmt.Errorf("invalid identifier: \"%s\"", identifier)
+	}
+	return f.formatter.Value(name)
+}
+
+// ValueModifier returns the value of the identifier without its prefix, letting the formatter interpret
+// the leading part of the modifier if it does.
+func (f *PrefixFormatter) ValueModifier(identifier string, modifier string) (any, string, error) {
+	name, ok := f.strip(identifier)
+	if !ok {
+		return nil, "", fmt.Errorf("invalid identifier: \"%s\"", identifier)
+	}
+	return valueModifier(f.formatter, name, modifier)
+}
+
+// ValidModifier returns the part of the modifier left to the modifiers.
+func (f *PrefixFormatter) ValidModifier(identifier string, modifier string) string {
+	name, _ := f.strip(identifier)
+	return validModifier(f.formatter, name, modifier)
+}
+
+// ValueContext returns the value of the identifier without its prefix, with the context when the
+// formatter accepts one.
+func (f *PrefixFormatter) ValueContext(ctx context.Context, identifier string) (any, error) {
+	name, ok := f.strip(identifier)
+	if !ok {
+		return nil, fmt.Errorf("invalid identifier: \"%s\"", identifier)
+	}
+	return valueContext(ctx, f.formatter, name)
+}
+
+// Values reads the identifiers at once when the formatter is a BatchFormatter.
+func (f *PrefixFormatter) Values(identifiers []string) (map[string]any, error) {
+	b, ok := asBatch(f.formatter)
+	if !ok {
+		return nil, nil
+	}
+	names := make([]string, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		if name, ok := f.strip(identifier); ok {
+			names = append(names, name)
+		}
+	}
+	values, err := b.Values(names)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]any, len(values))
+	for _, identifier := range identifiers {
+		name, _ := f.strip(identifier)
+		if v, ok := values[name]; ok {
+			result[identifier] = v
+		}
+	}
+	return result, nil
+}
+
+// Snapshot returns the formatter scoped under the prefix, in its snapshot when it has one.
+func (f *PrefixFormatter) Snapshot() Formatter {
+	if s, ok := f.formatter.(Snapshotter); ok {
+		return NewPrefixFormatter(f.prefix, s.Snapshot())
+	}
+	return f
+}
+
+// batches checks if the formatter reads values in batch.
+func (f *PrefixFormatter) batches() bool {
+	_, ok := asBatch(f.formatter)
+	return ok
+}
+
+// interprets checks if the formatter interprets the modifiers of the identifier without its prefix.
+func (f *PrefixFormatter) interprets(identifier string) bool {
+	name, _ := f.strip(identifier)
+	_, ok := asModifier(f.formatter, name)
+	return ok
+}
+
+// NewChainFormatter creates a new ChainFormatter consulting the formatters in the given order.
+func NewChainFormatter(formatters ...Formatter) *ChainFormatter {
+	return &ChainFormatter{
+		formatters: append([]Formatter{}, formatters...),
+	}
+}
+
+// NewOverrideFormatter creates a new ChainFormatter in which the values of the map, such as the data of a
+// request, shadow the identifiers of the formatter, such as global settings.
+func NewOverrideFormatter(formatter Formatter, values map[string]any) *ChainFormatter {
+	return NewChainFormatter(NewMapFormatter(values), formatter)
+}
+
+// ChainFormatter combines formatters into one: an identifier is read from the first formatter providing
+// it. The date arithmetic, snapshots, context and batches of the formatters are kept.
+type ChainFormatter struct {
+	formatters []Formatter
+}
+
+// formatter returns the first formatter providing the identifier, or nil.
+func (f *ChainFormatter) formatter(identifier string) Formatter {
+	for _, formatter := range f.formatters {
+		if formatter.Valid(identifier) {
+			return formatter
+		}
+	}
+	return nil
+}
+
+// Valid checks if one of the formatters provides the identifier.
+func (f *ChainFormatter) Valid(identifier string) bool {
+	return f.formatter(identifier) != nil
+}
+
+// Value returns the value of the identifier from the first formatter providing it.
+func (f *ChainFormatter) Value(identifier string) (any, error) {
+	formatter := f.formatter(identifier)
+	if formatter == nil {
+		return nil, fmt.Errorf("invalid identifier: \"%s\"", identifier)
+	}
+	return formatter.Value(identifier)
+}
+
+// ValueModifier returns the value of the identifier from the first formatter providing it, letting the
+// formatter interpret the leading part of the modifier if it does.
+func (f *ChainFormatter) ValueModifier(identifier string, modifier string) (any, string, error) {
+	formatter := f.formatter(identifier)
+	if formatter == nil {
+		return nil, "", fmt.Errorf("invalid identifier: \"%s\"", identifier)
+	}
+	return valueModifier(formatter, identifier, modifier)
+}
+
+// ValidModifier returns the part of the modifier left to the modifiers.
+func (f *ChainFormatter) ValidModifier(identifier string, modifier string) string {
+	return validModifier(f.formatter(identifier), identifier, modifier)
+}
+
+// ValueContext returns the value of the identifier from the first formatter providing it, with the
+// context when the formatter accepts one.
+func (f *ChainFormatter) ValueContext(ctx context.Context, identifier string) (any, error) {
+	formatter := f.formatter(identifier)
+	if formatter == nil {
+		return nil, fmt.Errorf("invalid identifier: \"%s\"", identifier)
+	}
+	return valueContext(ctx, formatter, identifier)
+}
+
+// Values reads the identifiers at once from the BatchFormatters providing them.
+func (f *ChainFormatter) Values(identifiers []string) (map[string]any, error) {
+	groups := make(map[int][]string)
+	for _, identifier := range identifiers {
+		for i, formatter := range f.formatters {
+			if formatter.Valid(identifier) {
+				groups[i] = append(groups[i], identifier)
+				break
+			}
+		}
+	}
+
+	result := make(map[string]any, len(identifiers))
+	for i, formatter := range f.formatters {
+		b, ok := asBatch(formatter)
+		if !ok || len(groups[i]) == 0 {
+			continue
+		}
+		values, err := b.Values(groups[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, identifier := range groups[i] {
+			if v, ok := values[identifier]; ok {
+				result[identifier] = v
+			}
+		}
+	}
+	return result, nil
+}
+
+// Snapshot returns the chain of the snapshots of the formatters.
+func (f *ChainFormatter) Snapshot() Formatter {
+	return &ChainFormatter{formatters: snapshot(f.formatters)}
+}
+
+// batches checks if one of the formatters reads values in batch.
+func (f *ChainFormatter) batches() bool {
+	for _, formatter := range f.formatters {
+		if _, ok := asBatch(formatter); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// interprets checks if the first formatter providing the identifier interprets its modifiers.
+func (f *ChainFormatter) interprets(identifier string) bool {
+	formatter := f.formatter(identifier)
+	if formatter == nil {
+		return false
+	}
+	_, ok := asModifier(formatter, identifier)
+	return ok
+}
+
+// composite is implemented by the formatters combining other formatters, which implement the optional
+// interfaces whether or not the formatters they combine do.
+type composite interface {
+	batches() bool
+	interprets(identifier string) bool
+}
+
+// asBatch returns the formatter as a BatchFormatter, unless it only combines formatters that do not
+// read values in batch.
+func asBatch(formatter Formatter) (BatchFormatter, bool) {
+	b, ok := formatter.(BatchFormatter)
+	if c, isComposite := formatter.(composite); ok && isComposite && !c.batches() {
+		return nil, false
+	}
+	return b, ok
+}
+
+// asModifier returns the formatter as a ModifierFormatter, unless it combines formatters of which the
+// one providing the identifier does not interpret modifiers.
+func asModifier(formatter Formatter, identifier string) (ModifierFormatter, bool) {
+	m, ok := formatter.(ModifierFormatter)
+	if c, isComposite := formatter.(composite); ok && isComposite && !c.interprets(identifier) {
+		return nil, false
+	}
+	return m, ok
+}
+
+// valueModifier reads the identifier from the formatter, letting it interpret the leading part of the
+// modifier if it does.
+func valueModifier(formatter Formatter, identifier string, modifier string) (any, string, error) {
+	if f, ok := formatter.(ModifierFormatter); ok {
+		return f.ValueModifier(identifier, modifier)
+	}
+	value, err := formatter.Value(identifier)
+	return value, modifier, err
+}
+
+// validModifier returns the part of the modifier the formatter leaves to the modifiers. A ModifierFormatter
+// that cannot tell leaves nothing to check.
+func validModifier(formatter Formatter, identifier string, modifier string) string {
+	if f, ok := formatter.(ModifierValidator); ok {
+		return f.ValidModifier(identifier, modifier)
+	}
+	if _, ok := formatter.(ModifierFormatter); ok {
+		return ""
+	}
+	return modifier
+}
+
+// valueContext reads the identifier from the formatter, with the context when it accepts one.
+func valueContext(ctx context.Context, formatter Formatter, identifier string) (any, error) {
+	if f, ok := formatter.(ContextFormatter); ok {
+		return f.ValueContext(ctx, identifier)
+	}
+	return formatter.Value(identifier)
+}
@@ -0,0 +1,125 @@
+package curly_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceebydith/curly"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposeFormatter(t *testing.T) {
+	rates := curly.NewFuncFormatter(func(identifier string) bool {
+		return curly.ValidIdentifier(identifier, []string{"usd", "sgd"})
+	}, func(identifier string) (any, error) {
+		switch strings.ToLower(identifier) {
+		case "usd":
+			return 15850, nil
+		case "sgd":
+			return 11790, nil
+		}
+		return nil, fmt.Errorf("invalid identifier: \"%s\"", identifier)
+	})
+	pln := curly.NewMapFormatter(map[string]any{"nama": "John Doe", "tagihan": 20075})
+	global := curly.NewMapFormatter(map[string]any{"app": "curly", "lang": "id"})
+
+	testFunc := formatterTester{
+		formatter: rates,
+		scenarios: []formatterScenarioTest{
+			{"usd", true, 15850, nil},
+			{"SGD", true, 11790, nil},
+			{"eur", false, nil, fmt.Errorf("invalid identifier: \"eur\"")},
+		},
+	}
+
+	testPrefix := formatterTester{
+		formatter: curly.NewPrefixFormatter("pln.", pln),
+		scenarios: []formatterScenarioTest{
+			{"pln.nama", true, "John Doe", nil},
+			{"PLN.Tagihan", true, 20075, nil},
+			{"nama", false, nil, fmt.Errorf("invalid identifier: \"nama\"")},
+			{"pln.", false, nil, fmt.Errorf("invalid identifier: \"pln.\"")},
+			{"pln.alamat", false, nil, fmt.Errorf("invalid identifier: \"alamat\"")},
+		},
+	}
+
+	testChain := formatterTester{
+		formatter: curly.NewChainFormatter(curly.NewPrefixFormatter("rate.", rates), curly.NewPrefixFormatter("pln.", pln), global),
+		scenarios: []formatterScenarioTest{
+			{"rate.usd", true, 15850, nil},
+			{"pln.nama", true, "John Doe", nil},
+			{"app", true, "curly", nil},
+			{"rate.eur", false, nil, fmt.Errorf("invalid identifier: \"rate.eur\"")},
+		},
+	}
+
+	testOverride := formatterTester{
+		formatter: curly.NewOverrideFormatter(global, map[string]any{"lang": "en", "trxid": "TRX-1"}),
+		scenarios: []formatterScenarioTest{
+			{"lang", true, "en", nil},
+			{"trxid", true, "TRX-1", nil},
+			{"app", true, "curly", nil},
+			{"user", false, nil, fmt.Errorf("invalid identifier: \"user\"")},
+		},
+	}
+
+	t.Run("TestFunc", testFunc.Test)
+	t.Run("TestPrefix", testPrefix.Test)
+	t.Run("TestChain", testChain.Test)
+	t.Run("TestOverride", testOverride.Test)
+}
+
+func TestComposeTemplate(t *testing.T) {
+	now := time.Date(2024, 12, 6, 6, 46, 20, 0, time.UTC)
+	data := curly.NewChainFormatter(
+		curly.NewPrefixFormatter("trx.", curly.NewDatetimeFormatterAt(now)),
+		curly.NewPrefixFormatter("dir.", curly.NewDirectoryFormatter()),
+		curly.NewOverrideFormatter(curly.NewMapFormatter(map[string]any{"app": "curly", "lang": "id"}), map[string]any{"lang": "en"}),
+	)
+
+	text, err := curly.Format("{app}-{lang}-{trx.yyyy}{trx.mm}{trx.dd}-{trx.date-1d|fmt(yyyy/mm/dd)}-{app|left(3)}", data)
+	require.NoError(t, err)
+	require.Equal(t, "curly-en-20241206-2024/12/05-cur", text)
+
+	curdir, err := os.Getwd()
+	require.NoError(t, err)
+	text, err = curly.Format("{dir.curdir}", data)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Clean(curdir), filepath.Clean(text))
+
+	require.NoError(t, curly.Validate("{trx.date-1d|fmt(yyyy/mm/dd)} {app|left(3)} {trx.yyyy}", data))
+	require.ErrorIs(t, curly.Validate("{app|upper()}", data), curly.ErrInvalidModifier)
+	require.ErrorIs(t, curly.Validate("{trx.date-1x}", data), curly.ErrInvalidModifier)
+	require.ErrorIs(t, curly.Validate("{trx.user}", data), curly.ErrUnknownIdentifier)
+
+	// A prefix keeps the batches of the formatter
+	customers := &batchFormatter{names: map[string]string{"customer.c001": "John Doe", "customer.c002": "Jane Doe"}}
+	text, err = curly.Format("{c.customer.c001|left(4)}/{c.customer.c002}", curly.NewChainFormatter(curly.NewPrefixFormatter("c.", customers)))
+	require.NoError(t, err)
+	require.Equal(t, "John/Jane Doe", text)
+	require.Equal(t, [][]string{{"customer.c001", "customer.c002"}}, customers.batches)
+	require.Zero(t, customers.values)
+}
+
+func TestFuncFormatterCalls(t *testing.T) {
+	calls := 0
+	rates := curly.NewFuncFormatter(func(identifier string) bool {
+		return curly.ValidIdentifier(identifier, []string{"usd"})
+	}, func(identifier string) (any, error) {
+		calls++
+		return 15850, nil
+	})
+
+	require.NoError(t, curly.Validate("{usd|money(,)} {usd}", rates))
+	require.ErrorIs(t, curly.Validate("{eur}", rates), curly.ErrUnknownIdentifier)
+	require.Zero(t, calls)
+
+	text, err := curly.Format("{usd}/{usd|money(,)}/{usd}", rates)
+	require.NoError(t, err)
+	require.Equal(t, "15850/15.850/15850", text)
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,296 @@
+package curly
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale holds the names of the months and days used to format dates.
+type Locale struct {
+	Months      [12]string // full month names, starting from January
+	ShortMonths [12]string // abbreviated month names, starting from January
+	Days        [7]string  // full day names, starting from Sunday
+	ShortDays   [7]string  // abbreviated day names, starting from Sunday
+	AM          string     // marker of the hours before noon
+	PM          string     // marker of the hours after noon
+}
+
+// Built-in locales.
+var (
+	LocaleEnglish = &Locale{
+		Months:      [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		ShortMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		Days:        [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		ShortDays:   [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		AM:          "AM",
+		PM:          "PM",
+	}
+	LocaleIndonesian = &Locale{
+		Months:      [12]string{"Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli", "Agustus", "September", "Oktober", "November", "Desember"},
+		ShortMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "Mei", "Jun", "Jul", "Agu", "Sep", "Okt", "Nov", "Des"},
+		Days:        [7]string{"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu"},
+		ShortDays:   [7]string{"Min", "Sen", "Sel", "Rab", "Kam", "Jum", "Sab"},
+		AM:          "AM",
+		PM:          "PM",
+	}
+)
+
+// formatDatetime returns the component of t named by the identifier, using the names of the locale
+// (English when nil). It reports false when the identifier is not a date or time identifier listed in
+// the documentation of DatetimeFormatter.
+func formatDatetime(identifier string, t time.Time, locale *Locale) (any, bool) {
+	if locale == nil {
+		locale = LocaleEnglish
+	}
+	switch identifier {
+	case "now":
+		return t.Format("2006-01-02 15:04:05"), true
+	case "date":
+		return t.Format("2006-01-02"), true
+	case "time":
+		return t.Format("15:04:05"), true
+	case "yyyy":
+		return t.Format("2006"), true
+	case "yy":
+		return t.Format("06"), true
+	case "mm":
+		return t.Format("01"), true
+	case "mmm":
+		return locale.ShortMonths[t.Month()-1], true
+	case "mmmm":
+		return locale.Months[t.Month()-1], true
+	case "dd":
+		return t.Format("02"), true
+	case "ddd":
+		return locale.ShortDays[t.Weekday()], true
+	case "dddd":
+		return locale.Days[t.Weekday()], true
+	case "doy":
+		return fmt.Sprintf("%03d", t.YearDay()), true
+	case "ww":
+		_, week := t.ISOWeek()
+		return fmt.Sprintf("%02d", week), true
+	case "wy":
+		year, _ := t.ISOWeek()
+		return fmt.Sprintf("%04d", year), true
+	case "hh":
+		return t.Format("15"), true
+	case "hh12":
+		return t.Format("03"), true
+	case "ampm":
+		if t.Hour() < 12 {
+			return locale.AM, true
+		}
+		return locale.PM, true
+	case "nn":
+		return t.Format("04"), true
+	case "ss":
+		return t.Format("05"), true
+	case "ms":
+		return fmt.Sprintf("%03d", t.Nanosecond()/int(time.Millisecond)), true
+	case "us":
+		return fmt.Sprintf("%06d", t.Nanosecond()/int(time.Microsecond)), true
+	case "unix":
+		return t.Unix(), true
+	case "unixms":
+		return t.UnixMilli(), true
+	}
+	return nil, false
+}
+
+// datetimeUnits maps the date and time identifiers to the unit of an offset without unit.
+var datetimeUnits = map[string]string{
+	"date": "d", "time": "s",
+	"yyyy": "y", "yy": "y", "wy": "y",
+	"mm": "M", "mmm": "M", "mmmm": "M",
+	"ww": "w",
+	"dd": "d", "ddd": "d", "dddd": "d", "doy": "d",
+	"hh": "h", "hh12": "h", "ampm": "h",
+	"nn": "m",
+	"ss": "s", "unix": "s",
+}
+
+// datetimeOffset is a single term of date arithmetic, such as -1d.
+type datetimeOffset struct {
+	n    int
+	unit string
+}
+
+// regexDatetimeOffsets matches the date arithmetic leading a modifier and the rest of the modifier.
+var (
+	regexDatetimeOffsets = regexp.MustCompile(`^:?((?:\s*[\+\-]\s*[0-9]+\s*(?:bd|[yMwdhms])?)+)\s*([\|:].*)?$`)
+	regexDatetimeOffset  = regexp.MustCompile(`([\+\-])\s*([0-9]+)\s*(bd|[yMwdhms])?`)
+)
+
+// parseDatetimeOffsets splits the modifier into its leading date arithmetic and the rest.
+// Units are y (years), M (months), w (weeks), d (days), bd (business days), h (hours), m (minutes)
+// and s (seconds); a term without unit uses the given unit. It reports false when the modifier does
+// not start with date arithmetic.
+func parseDatetimeOffsets(modifier string, unit string) ([]datetimeOffset, string, bool) {
+	match := regexDatetimeOffsets.FindStringSubmatch(modifier)
+	if match == nil {
+		return nil, modifier, false
+	}
+	var offsets []datetimeOffset
+	for _, term := range regexDatetimeOffset.FindAllStringSubmatch(match[1], -1) {
+		n, err := strconv.Atoi(term[2])
+		if err != nil {
+			return nil, modifier, false
+		}
+		if term[1] == "-" {
+			n = -n
+		}
+		u := term[3]
+		if u == "" {
+			if unit == "" {
+				return nil, modifier, false
+			}
+			u = unit
+		}
+		offsets = append(offsets, datetimeOffset{n: n, unit: u})
+	}
+	return offsets, strings.TrimSpace(match[2]), true
+}
+
+// shiftDatetime moves t by n units. Shifting by months or years keeps the day within the target
+// month, so January 31 plus one month is the last day of February.
+func shiftDatetime(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "y":
+		return addMonths(t, 12*n)
+	case "M":
+		return addMonths(t, n)
+	case "w":
+		return t.AddDate(0, 0, 7*n)
+	case "d":
+		return t.AddDate(0, 0, n)
+	case "bd":
+		step := 1
+		if n < 0 {
+			step = -1
+		}
+		for n != 0 {
+			t = t.AddDate(0, 0, step)
+			if t.Weekday() != time.Saturday && t.Weekday() != time.Sunday {
+				n -= step
+			}
+		}
+		return t
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour)
+	case "m":
+		return t.Add(time.Duration(n) * time.Minute)
+	case "s":
+		return t.Add(time.Duration(n) * time.Second)
+	}
+	return t
+}
+
+// addMonths adds n months to t, clamping the day to the last day of the target month.
+func addMonths(t time.Time, n int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// datetimeLayouts maps the names of the predefined layouts to their Go layout.
+var datetimeLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"iso8601":     "2006-01-02T15:04:05Z07:00",
+	"kitchen":     time.Kitchen,
+	"datetime":    time.DateTime,
+	"dateonly":    time.DateOnly,
+	"timeonly":    time.TimeOnly,
+}
+
+// layoutTokens lists the identifiers recognised in a layout, longest first.
+var layoutTokens = []string{
+	"unixms", "yyyy", "mmmm", "dddd", "hh12", "ampm", "unix",
+	"mmm", "ddd", "doy",
+	"yy", "mm", "dd", "ww", "wy", "hh", "nn", "ss", "ms", "us",
+}
+
+// datetimeLayout renders a time either with a predefined Go layout or with a curly layout.
+type datetimeLayout struct {
+	golayout string
+	layout   string
+}
+
+// regexDatetimeLayout matches the layout leading a modifier, such as :rfc3339 or |date(yyyy-mm-dd),
+// and the rest of the modifier.
+var regexDatetimeLayout = regexp.MustCompile(`(?i)^(?::\s*([a-z0-9]+)|\|?\s*(?:date|fmt)\((.*?)\))\s*(\|.*)?$`)
+
+// parseDatetimeLayout splits the modifier into its leading layout and the rest. It reports false when
+// the modifier does not start with a layout.
+func parseDatetimeLayout(modifier string) (datetimeLayout, string, bool) {
+	match := regexDatetimeLayout.FindStringSubmatch(modifier)
+	if match == nil {
+		return datetimeLayout{}, modifier, false
+	}
+	rest := strings.TrimSpace(match[3])
+	if match[1] != "" {
+		golayout, ok := datetimeLayouts[strings.ToLower(match[1])]
+		if !ok {
+			return datetimeLayout{}, modifier, false
+		}
+		return datetimeLayout{golayout: golayout}, rest, true
+	}
+	if golayout, ok := datetimeLayouts[strings.ToLower(strings.TrimSpace(match[2]))]; ok {
+		return datetimeLayout{golayout: golayout}, rest, true
+	}
+	return datetimeLayout{layout: match[2]}, rest, true
+}
+
+// format renders t with the layout. In a curly layout the lowercase date and time identifiers are
+// replaced by their value, text between quotes and characters after a backslash are kept as is.
+func (l datetimeLayout) format(t time.Time, locale *Locale) string {
+	if l.golayout != "" {
+		return t.Format(l.golayout)
+	}
+	var sb strings.Builder
+	layout := l.layout
+	for i := 0; i < len(layout); {
+		c := layout[i]
+		if c == '\\' && i+1 < len(layout) {
+			sb.WriteByte(layout[i+1])
+			i += 2
+			continue
+		}
+		if c == '"' || c == '\'' {
+			end := strings.IndexByte(layout[i+1:], c)
+			if end < 0 {
+				end = len(layout) - i - 1
+			}
+			sb.WriteString(layout[i+1 : i+1+end])
+			i += end + 2
+			continue
+		}
+		token := ""
+		for _, tok := range layoutTokens {
+			if strings.HasPrefix(layout[i:], tok) {
+				token = tok
+				break
+			}
+		}
+		if token == "" {
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+		value, _ := formatDatetime(token, t, locale)
+		sb.WriteString(fmt.Sprintf("%v", value))
+		i += len(token)
+	}
+	return sb.String()
+}
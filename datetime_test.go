@@ -0,0 +1,125 @@
+package curly_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ceebydith/curly"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatetime(t *testing.T) {
+	at := time.Date(2024, 12, 30, 18, 6, 20, 123456789, time.UTC)
+
+	testEnglish := formatterTester{
+		formatter: curly.NewDatetimeFormatterAt(at),
+		scenarios: []formatterScenarioTest{
+			{"mmm", true, "Dec", nil},
+			{"mmmm", true, "December", nil},
+			{"ddd", true, "Mon", nil},
+			{"dddd", true, "Monday", nil},
+			{"doy", true, "365", nil},
+			{"ww", true, "01", nil},
+			{"wy", true, "2025", nil},
+			{"hh12", true, "06", nil},
+			{"ampm", true, "PM", nil},
+			{"ms", true, "123", nil},
+			{"us", true, "123456", nil},
+			{"unix", true, int64(1735581980), nil},
+			{"unixms", true, int64(1735581980123), nil},
+		},
+	}
+
+	testIndonesian := formatterTester{
+		formatter: curly.NewDatetimeFormatterAt(at, curly.WithLocale(curly.LocaleIndonesian)),
+		scenarios: []formatterScenarioTest{
+			{"mmm", true, "Des", nil},
+			{"mmmm", true, "Desember", nil},
+			{"ddd", true, "Sen", nil},
+			{"dddd", true, "Senin", nil},
+			{"wib.ddd", true, "Sel", nil},
+		},
+	}
+
+	t.Run("TestDatetimeEnglish", testEnglish.Test)
+	t.Run("TestDatetimeIndonesian", testIndonesian.Test)
+}
+
+func TestDatetimeFormat(t *testing.T) {
+	formatter := curly.NewDatetimeFormatterAt(time.Date(2024, 12, 6, 6, 46, 20, 1000000, time.UTC), curly.WithLocale(curly.LocaleIndonesian))
+
+	text, err := curly.Format("{yyyy}{mm}{dd}{hh}{nn}{ss}{ms}", formatter)
+	require.NoError(t, err)
+	require.Equal(t, "20241206064620001", text)
+
+	text, err = curly.Format("{dddd}, {dd} {mmmm} {yyyy} {hh12}:{nn} {ampm}", formatter)
+	require.NoError(t, err)
+	require.Equal(t, "Jumat, 06 Desember 2024 06:46 AM", text)
+
+	text, err = curly.Format("log/{wy}-W{ww}/{unix}.log", formatter)
+	require.NoError(t, err)
+	require.Equal(t, "log/2024-W49/1733467580.log", text)
+}
+
+func TestDatetimeOffset(t *testing.T) {
+	testOffset := templateTester{
+		formatters: []curly.Formatter{curly.NewDatetimeFormatterAt(time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC))},
+		scenarios: []templateScenarioTest{
+			{"{dd-1}", "29", nil},
+			{"{dd-1d}/{mm-1d}", "29/02", nil},
+			{"{date-1d}", "2024-02-29", nil},
+			{"{date:+1y}", "2025-03-01", nil},
+			{"{date-1d+1y}", "2025-02-28", nil},
+			{"{mm-3}/{yyyy-3M}", "12/2023", nil},
+			{"{date+1bd}", "2024-03-04", nil},
+			{"{date-1bd}", "2024-02-29", nil},
+			{"{hh-1h}:{nn+45m}", "23:15", nil},
+			{"{dd - 1 d|pre(D)}", "D29", nil},
+			{"{dd*2}", "2", nil},
+			{"{ms+1}", "1", nil},
+			{"{ms+1d}", "000", nil},
+			{"{dd-1x}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidModifier, Text: "{dd-1x}", Offset: 0, Line: 1, Column: 1, Identifier: "dd", Modifier: "-1x",
+				Err: &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "-1x"},
+			}},
+		},
+	}
+
+	testMonthEnd := templateTester{
+		formatters: []curly.Formatter{curly.NewDatetimeFormatterAt(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC))},
+		scenarios: []templateScenarioTest{
+			{"{date+1M}", "2024-02-29", nil},
+			{"{date+2M}", "2024-03-31", nil},
+			{"{date-2M}", "2023-11-30", nil},
+		},
+	}
+
+	t.Run("TestDatetimeOffset", testOffset.Test)
+	t.Run("TestDatetimeMonthEnd", testMonthEnd.Test)
+}
+
+func TestDatetimeLayout(t *testing.T) {
+	testLayout := templateTester{
+		formatters: []curly.Formatter{curly.NewDatetimeFormatterAt(time.Date(2024, 12, 6, 6, 46, 20, 0, time.UTC), curly.WithLocale(curly.LocaleIndonesian))},
+		scenarios: []templateScenarioTest{
+			{"{now}", "2024-12-06 06:46:20", nil},
+			{"{now|date(yyyy-mm-dd hh:nn:ss)}", "2024-12-06 06:46:20", nil},
+			{"{now|fmt(ddmmyyyy)}", "06122024", nil},
+			{"{now|date(dddd, dd mmmm yyyy)}", "Jumat, 06 Desember 2024", nil},
+			{"{now|date(yyyy-mm-ddThh:nn:ss.ms)}", "2024-12-06T06:46:20.000", nil},
+			{"{now|date(\"Date:\" dd/mm \\ddd)}", "Date: 06/12 d06", nil},
+			{"{now:rfc3339}", "2024-12-06T06:46:20Z", nil},
+			{"{now:RFC1123}", "Fri, 06 Dec 2024 06:46:20 UTC", nil},
+			{"{now|date(iso8601)}", "2024-12-06T06:46:20Z", nil},
+			{"{now-1d:dateonly}", "2024-12-05", nil},
+			{"{date-1d|fmt(yyyy/mm/dd)|post(.log)}", "2024/12/05.log", nil},
+			{"{wib.now|date(hh:nn)} WIB", "13:46 WIB", nil},
+			{"{now:unknown}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidModifier, Text: "{now:unknown}", Offset: 0, Line: 1, Column: 1, Identifier: "now", Modifier: ":unknown",
+				Err: &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: ":unknown"},
+			}},
+		},
+	}
+
+	t.Run("TestDatetimeLayout", testLayout.Test)
+}
@@ -0,0 +1,314 @@
+package curly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Engine carries its own modifiers, formatters and parsers, so that independent parts of a program
+// can format and parse text with different settings. The package-level functions use an engine that
+// consults DefaultModifier.
+type Engine struct {
+	modifiers      []Modifier
+	formatters     []Formatter
+	parsers        []Parser
+	emptyAsMissing bool
+	missing        MissingMode
+	partials       PartialLoader
+	parallel       int
+}
+
+// MissingMode tells how a template renders a placeholder whose identifier no formatter provides.
+type MissingMode int
+
+const (
+	// MissingError fails the rendering with ErrUnknownIdentifier.
+	MissingError MissingMode = iota
+	// MissingKeep leaves the placeholder untouched, without using its default value, so that it can be
+	// rendered by a later stage. The braces and backslashes of the rest of the text are escaped, so that
+	// the result is itself a template.
+	MissingKeep
+	// MissingEmpty replaces the placeholder with an empty string.
+	MissingEmpty
+)
+
+// EngineOption configures an Engine.
+type EngineOption func(e *Engine)
+
+// WithModifiers sets the modifiers of the engine. Only these modifiers are available to the templates
+// and expressions of the engine; passing none disables modifiers entirely.
+func WithModifiers(modifiers ...Modifier) EngineOption {
+	return func(e *Engine) {
+		e.modifiers = append([]Modifier{}, modifiers...)
+	}
+}
+
+// WithFormatters adds formatters consulted after the formatters given to each call.
+func WithFormatters(formatters ...Formatter) EngineOption {
+	return func(e *Engine) {
+		e.formatters = append(e.formatters, formatters...)
+	}
+}
+
+// WithParsers adds parsers consulted after the parsers given to each call.
+func WithParsers(parsers ...Parser) EngineOption {
+	return func(e *Engine) {
+		e.parsers = append(e.parsers, parsers...)
+	}
+}
+
+// WithEmptyAsMissing makes the fallbacks of a placeholder, such as {nickname ?? name} or
+// {info|default(-)}, also replace values that are nil or empty.
+func WithEmptyAsMissing() EngineOption {
+	return func(e *Engine) {
+		e.emptyAsMissing = true
+	}
+}
+
+// WithMissing sets how the templates of the engine render unknown identifiers. Use FormatUnresolved or
+// ExecuteUnresolved to learn which identifiers were left unresolved.
+func WithMissing(mode MissingMode) EngineOption {
+	return func(e *Engine) {
+		e.missing = mode
+	}
+}
+
+// WithPartials sets the loader of the partials included by the templates of the engine with {>name}.
+// Partials are loaded when a template is compiled.
+func WithPartials(loader PartialLoader) EngineOption {
+	return func(e *Engine) {
+		e.partials = loader
+	}
+}
+
+// WithParallelLookups makes the templates of the engine read the values of the identifiers they use with
+// up to n concurrent lookups before rendering, so that slow formatters are not waited for one after
+// another. The formatters must then be safe for concurrent use. Identifiers used only by sections that
+// are not rendered may be read as well.
+func WithParallelLookups(n int) EngineOption {
+	return func(e *Engine) {
+		e.parallel = n
+	}
+}
+
+// NewEngine creates a new Engine configured with the provided options.
+// Without WithModifiers the engine uses the modifiers of DefaultModifier.
+func NewEngine(options ...EngineOption) *Engine {
+	e := &Engine{}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// defaultEngine is the engine used by the package-level functions.
+var defaultEngine = NewEngine()
+
+// Modifiers returns the modifiers available to the engine.
+func (e *Engine) Modifiers() []Modifier {
+	if e.modifiers == nil {
+		return DefaultModifier()
+	}
+	return e.modifiers
+}
+
+// Compile parses the placeholders of the given text and returns a Template executed by the engine.
+func (e *Engine) Compile(text string) (*Template, error) {
+	nodes, err := parseTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.includePartials(text, nodes, nil, map[string]*partialNode{}); err != nil {
+		return nil, err
+	}
+	return &Template{
+		engine: e,
+		text:   text,
+		nodes:  nodes,
+	}, nil
+}
+
+// CompilePattern analyses the parse expressions and returns a Pattern matched by the engine.
+func (e *Engine) CompilePattern(expressions []string, parsers ...Parser) (*Pattern, error) {
+	parsers = append(parsers[:len(parsers):len(parsers)], e.parsers...)
+	parsers = append(parsers, NewStringParser())
+
+	p := &Pattern{engine: e}
+	for i, expression := range expressions {
+		seg, err := compileSegment(i, expression, parsers)
+		if err != nil {
+			return nil, err
+		}
+		p.segments = append(p.segments, seg)
+	}
+	return p, nil
+}
+
+// Format applies the given formatters, followed by the formatters of the engine, to the text.
+func (e *Engine) Format(text string, formatters ...Formatter) (string, error) {
+	t, err := e.Compile(text)
+	if err != nil {
+		return "", err
+	}
+	return t.Execute(formatters...)
+}
+
+// FormatContext is like Format but reads the values of ContextFormatters with the given context, and
+// stops at the first lookup once the context is done.
+func (e *Engine) FormatContext(ctx context.Context, text string, formatters ...Formatter) (string, error) {
+	t, err := e.Compile(text)
+	if err != nil {
+		return "", err
+	}
+	return t.ExecuteContext(ctx, formatters...)
+}
+
+// FormatUnresolved is like Format but also returns the identifiers left unresolved according to the
+// MissingMode of the engine.
+func (e *Engine) FormatUnresolved(text string, formatters ...Formatter) (string, []string, error) {
+	t, err := e.Compile(text)
+	if err != nil {
+		return "", nil, err
+	}
+	return t.ExecuteUnresolved(formatters...)
+}
+
+// Validate checks the text against the given formatters, followed by the formatters of the engine,
+// without reading any value: every identifier must be provided by a formatter, every modifier accepted by
+// the modifiers of the engine and every arithmetic well formed. All the problems found are returned as
+// TemplateErrors joined with errors.Join.
+func (e *Engine) Validate(text string, formatters ...Formatter) error {
+	t, err := e.Compile(text)
+	if err != nil {
+		return err
+	}
+	return t.Validate(formatters...)
+}
+
+// Parse extracts data from text based on the provided expressions, the given parsers and the parsers of the engine.
+func (e *Engine) Parse(text string, expressions []string, parsers ...Parser) (map[string]any, error) {
+	p, err := e.CompilePattern(expressions, parsers...)
+	if err != nil {
+		return nil, err
+	}
+	return p.Match(text)
+}
+
+// NumberCalculate evaluates a mathematical expression after formatting it with the engine, using the
+// NumberModifier of the engine.
+func (e *Engine) NumberCalculate(expression string, formatters ...Formatter) (any, error) {
+	expression, err := e.Format(expression, formatters...)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := engineModifier[*NumberModifier](e)
+	if !ok {
+		return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
+	}
+	return m.Modify("", expression, e.execModifier)
+}
+
+// StringModify applies modifications to the given text after formatting the expression with the engine,
+// using the StringModifier of the engine.
+func (e *Engine) StringModify(text string, expression string, formatters ...Formatter) (string, error) {
+	expression, err := e.Format(expression, formatters...)
+	if err != nil {
+		return "", err
+	}
+	m, ok := engineModifier[*StringModifier](e)
+	if !ok {
+		return "", &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
+	}
+	val, err := m.Modify(text, expression, e.execModifier)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v", val), nil
+}
+
+// engineModifier returns the modifier of the engine of the given type.
+func engineModifier[T Modifier](e *Engine) (T, bool) {
+	for _, m := range e.Modifiers() {
+		if t, ok := m.(T); ok {
+			return t, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// formattersFor returns the formatters consulted for a call, falling back to the date, time and
+// directory formatters when neither the call nor the engine provides any.
+func (e *Engine) formattersFor(formatters []Formatter) []Formatter {
+	if len(e.formatters) != 0 {
+		formatters = append(formatters[:len(formatters):len(formatters)], e.formatters...)
+	}
+	if len(formatters) == 0 {
+		formatters = []Formatter{NewDatetimeFormatter(), NewDirectoryFormatter()}
+	}
+	return formatters
+}
+
+// checkModifier checks that the modifiers of the engine accept every part of the modifier expression,
+// and that an arithmetic expression can be evaluated.
+func (e *Engine) checkModifier(modifier string) error {
+	if modifier == "" {
+		return nil
+	}
+
+	var modif Modifier
+	for _, m := range e.Modifiers() {
+		if m.Valid(modifier) {
+			modif = m
+			break
+		}
+	}
+	if modif == nil {
+		return &ModifierError{Kind: ErrInvalidModifier, Expression: modifier}
+	}
+
+	// The result of an arithmetic expression does not depend on the value but its leading term
+	if m, ok := modif.(*NumberModifier); ok {
+		_, err := m.Modify("1", modifier)
+		if errors.Is(err, ErrInvalidModifier) || errors.Is(err, ErrDivisionByZero) {
+			return err
+		}
+		return nil
+	}
+
+	// Modifiers hand the parts of a chain they do not know over to the other modifiers
+	for _, part := range stringSplit(strings.Trim(modifier, " |")) {
+		valid := false
+		for _, m := range e.Modifiers() {
+			if m.Valid(part) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return &ModifierError{Kind: ErrInvalidModifier, Expression: strings.TrimSpace(part)}
+		}
+	}
+	return nil
+}
+
+// execModifier applies the first modifier of the engine accepting the modifier expression to the value.
+func (e *Engine) execModifier(value any, modifier string) (any, error) {
+	if modifier == "" {
+		return value, nil
+	}
+
+	var modif Modifier
+	for _, m := range e.Modifiers() {
+		if m.Valid(modifier) {
+			modif = m
+			break
+		}
+	}
+	if modif == nil {
+		return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: modifier}
+	}
+	return modif.Modify(fmt.Sprintf("%v", value), modifier, e.execModifier)
+}
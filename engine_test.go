@@ -0,0 +1,92 @@
+package curly_test
+
+import (
+	"testing"
+
+	"github.com/ceebydith/curly"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine(t *testing.T) {
+	data := curly.NewMapFormatter(map[string]any{"name": "John Doe", "amount": 150000})
+
+	// A sandboxed engine only knows the formatting modifiers
+	sandbox := curly.NewEngine(curly.WithModifiers(curly.NewFormatModifier()), curly.WithFormatters(data))
+	text, err := sandbox.Format("{name|left(10)}|{amount|money(,)}")
+	require.NoError(t, err)
+	require.Equal(t, "John Doe  |150.000", text)
+
+	_, err = sandbox.Format("{amount*2}")
+	require.ErrorIs(t, err, curly.ErrInvalidModifier)
+
+	// An engine without modifiers only substitutes values
+	plain := curly.NewEngine(curly.WithModifiers())
+	_, err = plain.Format("{name|left(10)}", data)
+	require.ErrorIs(t, err, curly.ErrInvalidModifier)
+
+	// The default engine is not affected by the other engines
+	text, err = curly.Format("{amount*2}", data)
+	require.NoError(t, err)
+	require.Equal(t, "300000", text)
+
+	// Formatters of the call take precedence over the formatters of the engine
+	text, err = sandbox.Format("{name}", curly.NewMapFormatter(map[string]any{"name": "Jane Doe"}))
+	require.NoError(t, err)
+	require.Equal(t, "Jane Doe", text)
+
+	// The calculations and modifications are restricted to the modifiers of the engine
+	_, err = sandbox.NumberCalculate("{amount} + 5000")
+	require.ErrorIs(t, err, curly.ErrInvalidModifier)
+
+	_, err = sandbox.StringModify("INV", "post(/{name})")
+	require.ErrorIs(t, err, curly.ErrInvalidModifier)
+
+	numbers := curly.NewEngine(curly.WithModifiers(curly.NewNumberModifier(), curly.NewStringModifier()), curly.WithFormatters(data))
+	total, err := numbers.NumberCalculate("{amount} + 5000")
+	require.NoError(t, err)
+	require.Equal(t, int64(155000), total)
+
+	modified, err := numbers.StringModify("INV", "post(/{name})")
+	require.NoError(t, err)
+	require.Equal(t, "INV/John Doe", modified)
+
+	parser := curly.NewEngine(curly.WithParsers(curly.NewNumberParser("amount")), curly.WithModifiers(curly.NewNumberModifier()))
+	parsed, err := parser.Parse("Amount 150.000 paid by John", []string{"Amount {amount/1000} paid", "by {name}"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"amount": int64(150), "name": "John"}, parsed)
+
+	_, err = parser.Parse("Amount 150.000 paid by John", []string{"by {name|remove(J)}"})
+	require.ErrorIs(t, err, curly.ErrInvalidModifier)
+}
+
+func TestEngineMissing(t *testing.T) {
+	config := curly.NewMapFormatter(map[string]any{"appname": "curly", "dir": "C:\\logs"})
+	request := curly.NewMapFormatter(map[string]any{"trxid": "2189566", "amount": 20000})
+	text := "{dir}\\\\{appname}-{trxid}.log: {amount|money(,)} \\{ok\\} {amount|default(0)}"
+
+	// Staged rendering: the first stage keeps the placeholders it cannot resolve
+	staged := curly.NewEngine(curly.WithMissing(curly.MissingKeep))
+	partial, unresolved, err := staged.FormatUnresolved(text, config)
+	require.NoError(t, err)
+	require.Equal(t, "C:\\\\logs\\\\curly-{trxid}.log: {amount|money(,)} \\{ok\\} {amount|default(0)}", partial)
+	require.Equal(t, []string{"trxid", "amount"}, unresolved)
+
+	final, err := curly.Format(partial, request)
+	require.NoError(t, err)
+	require.Equal(t, "C:\\logs\\curly-2189566.log: 20.000 {ok} 20000", final)
+
+	// Lenient rendering replaces the unknown placeholders with an empty string
+	lenient := curly.NewEngine(curly.WithMissing(curly.MissingEmpty))
+	result, unresolved, err := lenient.FormatUnresolved(text, config)
+	require.NoError(t, err)
+	require.Equal(t, "C:\\logs\\curly-.log:  {ok} 0", result)
+	require.Equal(t, []string{"trxid", "amount"}, unresolved)
+
+	// Other errors are still reported
+	_, _, err = lenient.FormatUnresolved("{appname|super()} {trxid}", config)
+	require.ErrorIs(t, err, curly.ErrInvalidModifier)
+
+	_, unresolved, err = curly.MustCompile(text).ExecuteUnresolved(config, request)
+	require.NoError(t, err)
+	require.Empty(t, unresolved)
+}
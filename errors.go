@@ -0,0 +1,150 @@
+package curly
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kinds of failure reported by the package. Every error returned by Format, Parse, the compiled
+// Template and Pattern and the built-in modifiers matches one of them with errors.Is.
+var (
+	ErrInvalidExpression = errors.New("invalid expression")
+	ErrUnknownIdentifier = errors.New("unknown identifier")
+	ErrInvalidModifier   = errors.New("invalid modifier")
+	ErrInvalidNumber     = errors.New("invalid number")
+	ErrInvalidValue      = errors.New("invalid value")
+	ErrDivisionByZero    = errors.New("division by zero")
+	ErrNoMatch           = errors.New("no match")
+)
+
+// errorKinds lists the kinds in the order they are looked up by errorKind.
+var errorKinds = []error{
+	ErrDivisionByZero,
+	ErrInvalidNumber,
+	ErrInvalidModifier,
+	ErrUnknownIdentifier,
+	ErrInvalidValue,
+	ErrNoMatch,
+	ErrInvalidExpression,
+}
+
+// errorKind returns the kind of err, falling back to the given kind when err has none.
+func errorKind(err error, fallback error) error {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return fallback
+}
+
+// TemplateError describes a failure of a template, located at the offending placeholder.
+type TemplateError struct {
+	Kind       error  // one of the Err* kinds
+	Text       string // source of the offending placeholder
+	Offset     int    // byte offset of the placeholder in the template
+	Line       int    // line of the placeholder, starting at 1
+	Column     int    // column of the placeholder in characters, starting at 1
+	Identifier string // identifier of the placeholder, if any
+	Modifier   string // modifier of the placeholder, if any
+	Partial    string // name of the partial holding the placeholder, if any
+	Err        error  // underlying error, if any
+}
+
+// newTemplateError creates a TemplateError for the placeholder text found at offset in source.
+func newTemplateError(kind error, source string, offset int, text string, err error) *TemplateError {
+	line, column := position(source, offset)
+	return &TemplateError{
+		Kind:   kind,
+		Text:   text,
+		Offset: offset,
+		Line:   line,
+		Column: column,
+		Err:    err,
+	}
+}
+
+// Error returns the kind, the placeholder and its position.
+func (e *TemplateError) Error() string {
+	msg := fmt.Sprintf("%s: \"%s\" at line %d, column %d", e.Kind, e.Text, e.Line, e.Column)
+	if e.Partial != "" {
+		msg += fmt.Sprintf(" of partial \"%s\"", e.Partial)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the kind and the underlying error.
+func (e *TemplateError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// PatternError describes a failure of a parse expression, located in the offending segment.
+type PatternError struct {
+	Kind       error  // one of the Err* kinds
+	Segment    int    // index of the segment in the expression, starting at 0
+	Expression string // source of the segment
+	Offset     int    // byte offset of the placeholder in the segment, or 0
+	Identifier string // identifier of the placeholder, if any
+	Modifier   string // modifier of the placeholder, if any
+	Err        error  // underlying error, if any
+}
+
+// Error returns the kind, the segment and the position in it.
+func (e *PatternError) Error() string {
+	msg := fmt.Sprintf("%s: \"%s\" at segment %d, offset %d", e.Kind, e.Expression, e.Segment, e.Offset)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the kind and the underlying error.
+func (e *PatternError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// ModifierError describes a failure of a modifier.
+type ModifierError struct {
+	Kind       error  // one of the Err* kinds
+	Expression string // the offending expression
+	Err        error  // underlying error, if any
+}
+
+// Error returns the kind and the offending expression.
+func (e *ModifierError) Error() string {
+	msg := fmt.Sprintf("%s: \"%s\"", e.Kind, e.Expression)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the kind and the underlying error.
+func (e *ModifierError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// position returns the line and column, both starting at 1, of the byte offset in text.
+func position(text string, offset int) (line int, column int) {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	before := text[:offset]
+	line = strings.Count(before, "\n") + 1
+	column = utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+	return line, column
+}
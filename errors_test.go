@@ -0,0 +1,47 @@
+package curly_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ceebydith/curly"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrors(t *testing.T) {
+	formatter := curly.NewMapFormatter(map[string]any{"amount": 100, "name": "John Doe"})
+	_, err := curly.Format("Receipt\n\n  Amount: {amount|money(,)}\n  Total : {amount*100000000000000000}", formatter)
+	require.ErrorIs(t, err, curly.ErrInvalidNumber)
+
+	var templateErr *curly.TemplateError
+	require.True(t, errors.As(err, &templateErr))
+	require.Equal(t, 4, templateErr.Line)
+	require.Equal(t, 11, templateErr.Column)
+	require.Equal(t, "amount", templateErr.Identifier)
+	require.Equal(t, "*100000000000000000", templateErr.Modifier)
+	require.EqualError(t, err, "invalid number: \"{amount*100000000000000000}\" at line 4, column 11: invalid number: \"100*100000000000000000\": strconv.ParseInt: parsing \"10000000000000000000\": value out of range")
+
+	_, err = curly.Format("Hello {nickname}", formatter)
+	require.ErrorIs(t, err, curly.ErrUnknownIdentifier)
+	require.EqualError(t, err, "unknown identifier: \"{nickname}\" at line 1, column 7")
+
+	_, err = curly.Parse("Hello John Doe", []string{"Hello {name}", "age {age}"}, curly.NewNumberParser("age"))
+	require.ErrorIs(t, err, curly.ErrNoMatch)
+
+	var patternErr *curly.PatternError
+	require.True(t, errors.As(err, &patternErr))
+	require.Equal(t, 1, patternErr.Segment)
+	require.Equal(t, "age", patternErr.Identifier)
+
+	_, err = curly.Parse("Index 1", []string{"Index {index|sub(x)}"})
+	require.ErrorIs(t, err, curly.ErrInvalidModifier)
+
+	var modifierErr *curly.ModifierError
+	require.True(t, errors.As(err, &modifierErr))
+	require.Equal(t, "sub(x)", modifierErr.Expression)
+
+	_, err = curly.NumberCalculate("{amount} / (10 - 10)", formatter)
+	require.ErrorIs(t, err, curly.ErrDivisionByZero)
+	require.Equal(t, fmt.Sprintf("division by zero: \"%s\"", "100 / (10 - 10)"), err.Error())
+}
@@ -1,9 +1,12 @@
 package curly
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -15,16 +18,87 @@ type Formatter interface {
 	Value(identifier string) (any, error)
 }
 
-// NewMapFormatter creates a new MapFormatter with the provided map.
-func NewMapFormatter(maps map[string]any) *MapFormatter {
-	return &MapFormatter{
+// Snapshotter is implemented by formatters whose values depend on the moment they are read.
+// A template replaces such a formatter by its snapshot once per execution, so that all the
+// placeholders of the text agree with each other.
+type Snapshotter interface {
+	Snapshot() Formatter
+}
+
+// ModifierFormatter is implemented by formatters that interpret the leading part of a modifier
+// themselves, such as the date arithmetic of DatetimeFormatter. ValueModifier returns the value of the
+// identifier with that part applied and the rest of the modifier, which is applied by the modifiers.
+type ModifierFormatter interface {
+	Formatter
+	ValueModifier(identifier string, modifier string) (value any, rest string, err error)
+}
+
+// ContextFormatter is implemented by formatters whose lookups follow the cancellation and the deadline
+// of a context, such as those reading from a database.
+type ContextFormatter interface {
+	Formatter
+	ValueContext(ctx context.Context, identifier string) (any, error)
+}
+
+// BatchFormatter is implemented by formatters reading several values at once, such as with a single
+// database query. The identifiers Values leaves out of its result are read with Value.
+type BatchFormatter interface {
+	Formatter
+	Values(identifiers []string) (map[string]any, error)
+}
+
+// ModifierValidator is implemented by ModifierFormatters that can tell, without reading any value,
+// which leading part of a modifier they interpret. ValidModifier returns the rest of the modifier,
+// which is left to the modifiers.
+type ModifierValidator interface {
+	ValidModifier(identifier string, modifier string) (rest string)
+}
+
+// NewMapFormatter creates a new MapFormatter with the provided map. The keys are indexed once, so keys
+// added to the map afterwards are only found by their exact name.
+func NewMapFormatter(maps map[string]any, options ...MapOption) *MapFormatter {
+	f := &MapFormatter{
 		maps: maps,
 	}
+	for _, option := range options {
+		option(f)
+	}
+	if f.caseSensitive {
+		return f
+	}
+
+	f.index = make(map[string]string, len(maps))
+	for key := range maps {
+		lower := strings.ToLower(key)
+		other, ok := f.index[lower]
+		if !ok {
+			f.index[lower] = key
+			continue
+		}
+		if f.collisions == nil {
+			f.collisions = make(map[string][]string)
+		}
+		if f.collisions[lower] == nil {
+			f.collisions[lower] = []string{other}
+		}
+		f.collisions[lower] = append(f.collisions[lower], key)
+		sort.Strings(f.collisions[lower])
+	}
+	return f
 }
 
-// NewDatetimeFormatter creates a new DatetimeFormatter.
-func NewDatetimeFormatter() *DatetimeFormatter {
-	return &DatetimeFormatter{}
+// NewDatetimeFormatter creates a new DatetimeFormatter reading the current time.
+func NewDatetimeFormatter(options ...DatetimeOption) *DatetimeFormatter {
+	f := &DatetimeFormatter{}
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+// NewDatetimeFormatterAt creates a new DatetimeFormatter always formatting the given time.
+func NewDatetimeFormatterAt(t time.Time, options ...DatetimeOption) *DatetimeFormatter {
+	return NewDatetimeFormatter(append(options, WithClock(func() time.Time { return t }))...)
 }
 
 // NewDirectoryFormatter creates a new DirectoryFormatter.
@@ -32,68 +106,281 @@ func NewDirectoryFormatter() *DirectoryFormatter {
 	return &DirectoryFormatter{}
 }
 
-// MapFormatter formats values based on a map of identifiers.
+// NewEnvFormatter creates a new EnvFormatter resolving the identifiers starting with the given prefix,
+// such as "env.", to environment variables.
+func NewEnvFormatter(prefix string, options ...EnvOption) *EnvFormatter {
+	f := &EnvFormatter{
+		prefix: prefix,
+		lookup: os.LookupEnv,
+	}
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+// MapFormatter formats values based on a map of identifiers, matched regardless of case unless created
+// with WithCaseSensitive. An identifier matching no key is read as a dotted path such as pln.nama.
 type MapFormatter struct {
-	maps map[string]any
+	maps          map[string]any
+	caseSensitive bool
+	index         map[string]string   // lowercase key to key
+	collisions    map[string][]string // lowercase key to the keys differing only in case
+}
+
+// MapOption configures a MapFormatter.
+type MapOption func(f *MapFormatter)
+
+// WithCaseSensitive makes the identifiers match the keys of the map, and of its nested maps, exactly.
+func WithCaseSensitive() MapOption {
+	return func(f *MapFormatter) {
+		f.caseSensitive = true
+	}
+}
+
+// key returns the key of the map matching the identifier, or the keys it matches when they differ only
+// in case.
+func (f *MapFormatter) key(identifier string) (string, []string, bool) {
+	if _, ok := f.maps[identifier]; ok {
+		return identifier, nil, true
+	}
+	if f.caseSensitive {
+		return "", nil, false
+	}
+	lower := strings.ToLower(identifier)
+	if keys, ok := f.collisions[lower]; ok {
+		return "", keys, true
+	}
+	key, ok := f.index[lower]
+	return key, nil, ok
+}
+
+// path resolves a dotted identifier whose first segment is a key of the map, returning the value, or the
+// segment that could not be resolved, or the keys an ambiguous segment matches.
+func (f *MapFormatter) path(identifier string) (reflect.Value, string, []string, bool) {
+	head, rest, ok := strings.Cut(identifier, ".")
+	if !ok {
+		return reflect.Value{}, identifier, nil, false
+	}
+	key, keys, ok := f.key(head)
+	if !ok || keys != nil {
+		return reflect.Value{}, head, keys, ok
+	}
+	return resolvePath(reflect.ValueOf(f.maps[key]), rest, !f.caseSensitive)
 }
 
 // Valid checks if the identifier is valid in the map.
 func (f *MapFormatter) Valid(identifier string) bool {
-	identifier = strings.ToLower(identifier)
-	for key := range f.maps {
-		if strings.ToLower(key) == identifier {
-			return true
-		}
+	if _, keys, ok := f.key(identifier); ok {
+		return keys == nil
 	}
-	return false
+	_, _, keys, ok := f.path(identifier)
+	return ok && keys == nil
 }
 
 // Value returns the value associated with the identifier in the map.
 func (f *MapFormatter) Value(identifier string) (any, error) {
-	identifier = strings.ToLower(identifier)
-	for key, val := range f.maps {
-		if strings.ToLower(key) == identifier {
-			return val, nil
-		}
+	key, keys, ok := f.key(identifier)
+	if ok && keys == nil {
+		return f.maps[key], nil
+	}
+	v, missing := reflect.Value{}, ""
+	if !ok {
+		v, missing, keys, ok = f.path(identifier)
+	}
+	switch {
+	case ok && keys != nil:
+		return nil, ambiguityError(identifier, keys)
+	case ok:
+		return valueOf(v), nil
+	case f.caseSensitive:
+		return nil, pathError(identifier, missing)
+	}
+	return nil, pathError(strings.ToLower(identifier), strings.ToLower(missing))
+}
+
+// DatetimeOption configures a DatetimeFormatter.
+type DatetimeOption func(f *DatetimeFormatter)
+
+// WithClock sets the function used by a DatetimeFormatter to read the current time.
+func WithClock(clock func() time.Time) DatetimeOption {
+	return func(f *DatetimeFormatter) {
+		f.clock = clock
+	}
+}
+
+// WithLocation sets the time zone in which a DatetimeFormatter formats the time.
+func WithLocation(location *time.Location) DatetimeOption {
+	return func(f *DatetimeFormatter) {
+		f.location = location
+	}
+}
+
+// WithLocale sets the names of the months and days used by a DatetimeFormatter.
+func WithLocale(locale *Locale) DatetimeOption {
+	return func(f *DatetimeFormatter) {
+		f.locale = locale
 	}
-	return nil, fmt.Errorf("invalid identifier: \"%s\"", identifier)
 }
 
-// DatetimeFormatter formats values based on date and time.
-type DatetimeFormatter struct{}
+// DatetimeFormatter formats values based on date and time. It provides the following identifiers:
+//
+//	now         date and time as yyyy-mm-dd hh:nn:ss
+//	date, time  date as yyyy-mm-dd and time as hh:nn:ss
+//	yyyy, yy    year in four or two digits
+//	mm, dd      month and day in two digits
+//	mmm, mmmm   abbreviated and full month name
+//	ddd, dddd   abbreviated and full day name
+//	doy         day of the year in three digits
+//	ww, wy      ISO 8601 week number in two digits and its year in four digits
+//	hh, hh12    hour in two digits on the 24-hour and the 12-hour clock
+//	ampm        marker of the 12-hour clock
+//	nn, ss      minute and second in two digits
+//	ms, us      fraction of the second in milliseconds (three digits) and microseconds (six digits)
+//	unix        seconds elapsed since January 1, 1970 UTC
+//	unixms      milliseconds elapsed since January 1, 1970 UTC
+//
+// An identifier may select another time zone with a prefix such as utc.hh, local.hh, wib.hh, wita.hh
+// and wit.hh, or with a suffix naming the location such as hh@Asia/Makassar.
+type DatetimeFormatter struct {
+	clock    func() time.Time
+	location *time.Location
+	locale   *Locale
+}
+
+// now returns the current time according to the clock of the formatter.
+func (f *DatetimeFormatter) now() time.Time {
+	if f.clock == nil {
+		return time.Now()
+	}
+	return f.clock()
+}
+
+// Snapshot returns a DatetimeFormatter fixed at the current time of the formatter.
+func (f *DatetimeFormatter) Snapshot() Formatter {
+	snapshot := *f
+	now := f.now()
+	snapshot.clock = func() time.Time { return now }
+	return &snapshot
+}
+
+// parse splits the identifier into its date or time name and the location it is formatted in.
+func (f *DatetimeFormatter) parse(identifier string) (name string, location *time.Location, ok bool) {
+	name = strings.ToLower(identifier)
+	location = f.location
+	if i := strings.IndexByte(name, '@'); i >= 0 {
+		loc, err := loadLocation(identifier[i+1:])
+		if err != nil {
+			return "", nil, false
+		}
+		name, location = name[:i], loc
+	} else if i := strings.IndexByte(name, '.'); i >= 0 {
+		zone, found := datetimeZones[name[:i]]
+		if !found {
+			return "", nil, false
+		}
+		loc, err := loadLocation(zone)
+		if err != nil {
+			return "", nil, false
+		}
+		name, location = name[i+1:], loc
+	}
+	return name, location, true
+}
 
 // Valid checks if the identifier is a valid date or time identifier.
 func (f *DatetimeFormatter) Valid(identifier string) bool {
-	identifier = strings.ToLower(identifier)
-	identifiers := []string{"yyyy", "yy", "mm", "dd", "hh", "nn", "ss"}
-	for _, iden := range identifiers {
-		if identifier == iden {
-			return true
-		}
+	identifier, _, ok := f.parse(identifier)
+	if !ok {
+		return false
 	}
-	return false
+	_, ok = formatDatetime(identifier, time.Time{}, f.locale)
+	return ok
 }
 
 // Value returns the current date or time based on the identifier.
 func (f *DatetimeFormatter) Value(identifier string) (any, error) {
-	identifier = strings.ToLower(identifier)
-	switch identifier {
-	case "yyyy":
-		return time.Now().Format("2006"), nil
-	case "yy":
-		return time.Now().Format("06"), nil
-	case "mm":
-		return time.Now().Format("01"), nil
-	case "dd":
-		return time.Now().Format("02"), nil
-	case "hh":
-		return time.Now().Format("15"), nil
-	case "nn":
-		return time.Now().Format("04"), nil
-	case "ss":
-		return time.Now().Format("05"), nil
+	name, location, ok := f.parse(identifier)
+	if !ok {
+		return nil, fmt.Errorf("invalid identifier: \"%s\"", strings.ToLower(identifier))
 	}
-	return nil, fmt.Errorf("invalid identifier: \"%s\"", identifier)
+	now := f.now()
+	if location != nil {
+		now = now.In(location)
+	}
+	if value, ok := formatDatetime(name, now, f.locale); ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("invalid identifier: \"%s\"", strings.ToLower(identifier))
+}
+
+// ValidModifier returns the rest of the modifier after the date arithmetic and the layout interpreted
+// by ValueModifier.
+func (f *DatetimeFormatter) ValidModifier(identifier string, modifier string) string {
+	name, _, ok := f.parse(identifier)
+	if !ok {
+		return modifier
+	}
+	_, rest, _ := parseDatetimeOffsets(modifier, datetimeUnits[name])
+	_, rest, _ = parseDatetimeLayout(rest)
+	return rest
+}
+
+// ValueModifier returns the date or time based on the identifier, shifted by the date arithmetic
+// leading the modifier, such as {dd-1d}, {date+1M} or {hh+2h-30m}, and rendered with the layout
+// that may follow it, such as {now|date(yyyy-mm-dd hh:nn)} or {now-1d:rfc3339}. An offset without unit
+// moves the component of the identifier itself, so {dd-1} is the day before and {mm+1} the month after.
+func (f *DatetimeFormatter) ValueModifier(identifier string, modifier string) (any, string, error) {
+	name, location, ok := f.parse(identifier)
+	if !ok {
+		return nil, "", fmt.Errorf("invalid identifier: \"%s\"", strings.ToLower(identifier))
+	}
+	offsets, rest, shifted := parseDatetimeOffsets(modifier, datetimeUnits[name])
+	layout, rest, formatted := parseDatetimeLayout(rest)
+	if !shifted && !formatted {
+		value, err := f.Value(identifier)
+		return value, modifier, err
+	}
+
+	now := f.now()
+	if location != nil {
+		now = now.In(location)
+	}
+	for _, offset := range offsets {
+		now = shiftDatetime(now, offset.n, offset.unit)
+	}
+	if formatted {
+		return layout.format(now, f.locale), rest, nil
+	}
+	if value, ok := formatDatetime(name, now, f.locale); ok {
+		return value, rest, nil
+	}
+	return nil, "", fmt.Errorf("invalid identifier: \"%s\"", strings.ToLower(identifier))
+}
+
+// datetimeZones maps the time zone prefixes of date and time identifiers to their location.
+var datetimeZones = map[string]string{
+	"utc":   "UTC",
+	"local": "Local",
+	"wib":   "Asia/Jakarta",
+	"wita":  "Asia/Makassar",
+	"wit":   "Asia/Jayapura",
+}
+
+// locations caches the locations loaded by name.
+var locations sync.Map
+
+// loadLocation returns the location with the given name, loading it only once.
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
 }
 
 // DirectoryFormatter formats values based on the directory paths.
@@ -134,3 +421,70 @@ func (f *DirectoryFormatter) Value(identifier string) (any, error) {
 	}
 	return nil, fmt.Errorf("invalid identifier: \"%s\"", identifier)
 }
+
+// EnvOption configures an EnvFormatter.
+type EnvOption func(f *EnvFormatter)
+
+// WithAllowedEnv restricts an EnvFormatter to the named environment variables, so that a template
+// cannot read any other variable.
+func WithAllowedEnv(names ...string) EnvOption {
+	return func(f *EnvFormatter) {
+		if f.allowed == nil {
+			f.allowed = map[string]bool{}
+		}
+		for _, name := range names {
+			f.allowed[name] = true
+		}
+	}
+}
+
+// WithEnvMap makes an EnvFormatter read the variables from the given map instead of the environment
+// of the process, for example in tests.
+func WithEnvMap(env map[string]string) EnvOption {
+	return func(f *EnvFormatter) {
+		f.lookup = func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		}
+	}
+}
+
+// EnvFormatter formats values based on environment variables. The prefix of the identifier is matched
+// regardless of case, the name of the variable as is.
+type EnvFormatter struct {
+	prefix  string
+	allowed map[string]bool
+	lookup  func(name string) (string, bool)
+}
+
+// name returns the name of the environment variable of the identifier, and whether it may be read.
+func (f *EnvFormatter) name(identifier string) (string, bool) {
+	if len(identifier) <= len(f.prefix) || !strings.EqualFold(identifier[:len(f.prefix)], f.prefix) {
+		return "", false
+	}
+	name := identifier[len(f.prefix):]
+	if f.allowed != nil && !f.allowed[name] {
+		return "", false
+	}
+	return name, true
+}
+
+// Valid checks if the identifier names an allowed environment variable that is set.
+func (f *EnvFormatter) Valid(identifier string) bool {
+	name, ok := f.name(identifier)
+	if !ok {
+		return false
+	}
+	_, ok = f.lookup(name)
+	return ok
+}
+
+// Value returns the value of the environment variable named by the identifier.
+func (f *EnvFormatter) Value(identifier string) (any, error) {
+	if name, ok := f.name(identifier); ok {
+		if value, ok := f.lookup(name); ok {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid identifier: \"%s\"", identifier)
+}
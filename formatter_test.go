@@ -30,20 +30,118 @@ func TestFormatter(t *testing.T) {
 		},
 	}
 
+	testMapNested := formatterTester{
+		formatter: curly.NewMapFormatter(map[string]any{
+			"pln.idpel": "133312626789",
+			"pln": map[string]any{
+				"idpel": "000000000000",
+				"nama":  "John Doe",
+				"daya":  map[string]string{"tarif": "R1", "va": "1300"},
+			},
+			"items": []any{
+				map[string]any{"name": "Token 20K", "price": 20000},
+				map[string]any{"name": "Admin", "price": 2500},
+			},
+		}),
+		scenarios: []formatterScenarioTest{
+			{"pln.idpel", true, "133312626789", nil},
+			{"pln.nama", true, "John Doe", nil},
+			{"PLN.Daya.Tarif", true, "R1", nil},
+			{"items.1.price", true, 2500, nil},
+			{"items.0", true, map[string]any{"name": "Token 20K", "price": 20000}, nil},
+			{"pln.alamat", false, nil, fmt.Errorf("invalid identifier: \"pln.alamat\": missing segment \"alamat\"")},
+			{"pln.daya.kwh", false, nil, fmt.Errorf("invalid identifier: \"pln.daya.kwh\": missing segment \"kwh\"")},
+			{"items.2.price", false, nil, fmt.Errorf("invalid identifier: \"items.2.price\": missing segment \"2\"")},
+			{"items.first", false, nil, fmt.Errorf("invalid identifier: \"items.first\": missing segment \"first\"")},
+			{"bpjs.nama", false, nil, fmt.Errorf("invalid identifier: \"bpjs.nama\": missing segment \"bpjs\"")},
+		},
+	}
+
+	testMapCase := formatterTester{
+		formatter: curly.NewMapFormatter(map[string]any{
+			"Name":      "John Doe",
+			"name":      "john doe",
+			"NAME":      "JOHN DOE",
+			"TarifDaya": "R1/1300VA",
+			"pln":       map[string]any{"Nama": "Mbok Darmi"},
+			"bpjs":      map[string]any{"Nama": "Mbok Darmi", "NAMA": "MBOK DARMI"},
+		}),
+		scenarios: []formatterScenarioTest{
+			{"Name", true, "John Doe", nil},
+			{"name", true, "john doe", nil},
+			{"nAmE", false, nil, fmt.Errorf("ambiguous identifier: \"nAmE\" matches keys \"NAME\", \"Name\", \"name\"")},
+			{"nAmE.first", false, nil, fmt.Errorf("ambiguous identifier: \"nAmE.first\" matches keys \"NAME\", \"Name\", \"name\"")},
+			{"tarifdaya", true, "R1/1300VA", nil},
+			{"pln.nama", true, "Mbok Darmi", nil},
+			{"bpjs.NAMA", true, "MBOK DARMI", nil},
+			{"bpjs.nama", false, nil, fmt.Errorf("ambiguous identifier: \"bpjs.nama\" matches keys \"NAMA\", \"Nama\"")},
+			{"yyyy", false, nil, fmt.Errorf("invalid identifier: \"yyyy\"")},
+			{"pbb.nama", false, nil, fmt.Errorf("invalid identifier: \"pbb.nama\": missing segment \"pbb\"")},
+		},
+	}
+
+	testMapCaseSensitive := formatterTester{
+		formatter: curly.NewMapFormatter(map[string]any{
+			"Name":      "John Doe",
+			"name":      "john doe",
+			"TarifDaya": "R1/1300VA",
+			"pln":       map[string]any{"Nama": "Mbok Darmi"},
+		}, curly.WithCaseSensitive()),
+		scenarios: []formatterScenarioTest{
+			{"Name", true, "John Doe", nil},
+			{"name", true, "john doe", nil},
+			{"TarifDaya", true, "R1/1300VA", nil},
+			{"tarifdaya", false, nil, fmt.Errorf("invalid identifier: \"tarifdaya\"")},
+			{"pln.Nama", true, "Mbok Darmi", nil},
+			{"pln.nama", false, nil, fmt.Errorf("invalid identifier: \"pln.nama\": missing segment \"nama\"")},
+			{"PLN.Nama", false, nil, fmt.Errorf("invalid identifier: \"PLN.Nama\": missing segment \"PLN\"")},
+		},
+	}
+
 	testDatetime := formatterTester{
-		formatter: curly.NewDatetimeFormatter(),
+		formatter: curly.NewDatetimeFormatterAt(time.Date(2024, 12, 6, 6, 46, 20, 0, time.UTC)),
 		scenarios: []formatterScenarioTest{
-			{"yyyy", true, time.Now().Format("2006"), nil},
-			{"yy", true, time.Now().Format("06"), nil},
-			{"mm", true, time.Now().Format("01"), nil},
-			{"dd", true, time.Now().Format("02"), nil},
-			{"hh", true, time.Now().Format("15"), nil},
-			{"nn", true, time.Now().Format("04"), nil},
-			{"ss", true, time.Now().Format("05"), nil},
+			{"yyyy", true, "2024", nil},
+			{"yy", true, "24", nil},
+			{"mm", true, "12", nil},
+			{"dd", true, "06", nil},
+			{"hh", true, "06", nil},
+			{"nn", true, "46", nil},
+			{"ss", true, "20", nil},
 			{"xx", false, nil, fmt.Errorf("invalid identifier: \"xx\"")},
 		},
 	}
 
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	require.NoError(t, err)
+	testDatetimeLocation := formatterTester{
+		formatter: curly.NewDatetimeFormatterAt(time.Date(2024, 12, 6, 23, 46, 20, 0, time.UTC), curly.WithLocation(jakarta)),
+		scenarios: []formatterScenarioTest{
+			{"dd", true, "07", nil},
+			{"hh", true, "06", nil},
+			{"utc.dd", true, "06", nil},
+			{"UTC.hh", true, "23", nil},
+			{"wita.hh", true, "07", nil},
+			{"wit.hh", true, "08", nil},
+			{"hh@Asia/Makassar", true, "07", nil},
+			{"hh@America/Port-au-Prince", true, "18", nil},
+			{"mars.hh", false, nil, fmt.Errorf("invalid identifier: \"mars.hh\"")},
+			{"hh@Mars/Base", false, nil, fmt.Errorf("invalid identifier: \"hh@mars/base\"")},
+		},
+	}
+
+	clock := time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)
+	testDatetimeClock := formatterTester{
+		formatter: curly.NewDatetimeFormatter(curly.WithClock(func() time.Time {
+			clock = clock.Add(time.Second)
+			return clock
+		})),
+		scenarios: []formatterScenarioTest{
+			{"yyyy", true, "2025", nil},
+			{"ss", true, "01", nil},
+		},
+	}
+
 	testDirectory := formatterTester{
 		formatter: curly.NewDirectoryFormatter(),
 		scenarios: []formatterScenarioTest{
@@ -53,10 +151,42 @@ func TestFormatter(t *testing.T) {
 		},
 	}
 	t.Run("TestMapFormatter", testMap.Test)
+	t.Run("TestMapNestedFormatter", testMapNested.Test)
+	t.Run("TestMapCaseFormatter", testMapCase.Test)
+	t.Run("TestMapCaseSensitiveFormatter", testMapCaseSensitive.Test)
 	t.Run("TestDatetimeFormatter", testDatetime.Test)
+	t.Run("TestDatetimeLocationFormatter", testDatetimeLocation.Test)
+	t.Run("TestDatetimeClockFormatter", testDatetimeClock.Test)
 	t.Run("TestDirectoryFormatter", testDirectory.Test)
 }
 
+func TestMapFormatterAmbiguous(t *testing.T) {
+	data := curly.NewMapFormatter(map[string]any{"Name": 1, "name": 2, "pln": map[string]any{"Nama": "A", "NAMA": "B"}})
+
+	require.NoError(t, curly.Validate("{Name} {name} {pln.Nama}", data))
+	for _, text := range []string{"{nAme}", "{pln.nama}"} {
+		require.ErrorIs(t, curly.Validate(text, data), curly.ErrUnknownIdentifier)
+		_, err := curly.Format(text, data)
+		require.ErrorIs(t, err, curly.ErrUnknownIdentifier)
+	}
+}
+
+func BenchmarkMapFormatter(b *testing.B) {
+	data := map[string]any{}
+	for i := 0; i < 500; i++ {
+		data[fmt.Sprintf("biller.field_%03d", i)] = i
+	}
+	formatter := curly.NewMapFormatter(data)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = formatter.Valid("Biller.Field_250")
+		_, _ = formatter.Value("Biller.Field_250")
+		_, _ = formatter.Value("biller.field_499")
+		_ = formatter.Valid("yyyy")
+		_ = formatter.Valid("biller.field_999")
+	}
+}
+
 type formatterScenarioTest struct {
 	identifier  string
 	expectValid bool
@@ -81,3 +211,72 @@ func (tester *formatterTester) Test(t *testing.T) {
 		require.Equal(t, scenario.expectError, err, "Value "+msg)
 	}
 }
+
+func TestDatetimeSnapshot(t *testing.T) {
+	clock := time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)
+	formatter := curly.NewDatetimeFormatter(curly.WithClock(func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}))
+
+	tmpl := curly.MustCompile("{yyyy}{mm}{dd}{hh}{nn}{ss}")
+	text, err := tmpl.Execute(formatter)
+	require.NoError(t, err)
+	require.Equal(t, "20250101000000", text)
+
+	text, err = tmpl.Execute(formatter)
+	require.NoError(t, err)
+	require.Equal(t, "20250101000001", text)
+}
+
+func TestDatetimeLocation(t *testing.T) {
+	formatter := curly.NewDatetimeFormatterAt(time.Date(2024, 12, 6, 23, 46, 20, 0, time.UTC))
+	text, err := curly.Format("{wib.hh}:{wib.nn} WIB, {hh@Asia/Makassar}:{nn} WITA, {utc.hh}:{nn} UTC, {hh@UTC/2}", formatter)
+	require.NoError(t, err)
+	require.Equal(t, "06:46 WIB, 07:46 WITA, 23:46 UTC, 11.5", text)
+}
+
+func TestEnvFormatter(t *testing.T) {
+	testEnv := formatterTester{
+		formatter: curly.NewEnvFormatter("env.", curly.WithEnvMap(map[string]string{
+			"HOME":       "/home/john",
+			"APP_ENV":    "production",
+			"__SECRET__": "p455w0rd",
+			"empty":      "",
+		})),
+		scenarios: []formatterScenarioTest{
+			{"env.HOME", true, "/home/john", nil},
+			{"ENV.APP_ENV", true, "production", nil},
+			{"env.__SECRET__", true, "p455w0rd", nil},
+			{"env.empty", true, "", nil},
+			{"env.home", false, nil, fmt.Errorf("invalid identifier: \"env.home\"")},
+			{"HOME", false, nil, fmt.Errorf("invalid identifier: \"HOME\"")},
+			{"env.", false, nil, fmt.Errorf("invalid identifier: \"env.\"")},
+		},
+	}
+
+	testEnvAllowed := formatterTester{
+		formatter: curly.NewEnvFormatter("env.", curly.WithAllowedEnv("HOME", "APP_ENV"), curly.WithEnvMap(map[string]string{
+			"HOME":       "/home/john",
+			"APP_ENV":    "production",
+			"__SECRET__": "p455w0rd",
+		})),
+		scenarios: []formatterScenarioTest{
+			{"env.HOME", true, "/home/john", nil},
+			{"env.APP_ENV", true, "production", nil},
+			{"env.__SECRET__", false, nil, fmt.Errorf("invalid identifier: \"env.__SECRET__\"")},
+		},
+	}
+
+	t.Run("TestEnvFormatter", testEnv.Test)
+	t.Run("TestEnvAllowedFormatter", testEnvAllowed.Test)
+
+	t.Setenv("HOME", "/home/john")
+	t.Setenv("CURLY_APP_ENV", "staging")
+	text, err := curly.Format("{env.HOME}/.app/{env.CURLY_APP_ENV}/config.json", curly.NewEnvFormatter("env."))
+	require.NoError(t, err)
+	require.Equal(t, "/home/john/.app/staging/config.json", text)
+
+	_, err = curly.Format("{env.CURLY_APP_ENV}", curly.NewEnvFormatter("env.", curly.WithAllowedEnv("HOME")))
+	require.ErrorIs(t, err, curly.ErrUnknownIdentifier)
+}
@@ -2,6 +2,7 @@ package curly
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -34,7 +35,7 @@ func numberOf(num string) (float64, error) {
 	n := strings.ReplaceAll(num, " ", "")
 	v, err := strconv.ParseFloat(n, 64)
 	if err != nil {
-		return 0, fmt.Errorf("invalid number: \"%s\"", num)
+		return 0, fmt.Errorf("%w: \"%s\"", ErrInvalidNumber, num)
 	}
 	return v, nil
 }
@@ -59,7 +60,7 @@ func calculate(a string, opr string, b string) (float64, error) {
 	case "/":
 		return x / y, nil
 	}
-	return 0, fmt.Errorf("invalid operator: \"%s\"", opr)
+	return 0, fmt.Errorf("%w: invalid operator \"%s\"", ErrInvalidModifier, opr)
 }
 
 // stringSplit splits a string or slice of strings based on the provided delimiter.
@@ -97,21 +98,56 @@ func stringJoin[T string | []string](str T) string {
 	return ""
 }
 
-// execModifier applies a modifier to a value and returns the modified value.
-func execModifier(value any, modifier string) (any, error) {
-	if modifier == "" {
-		return value, nil
+// escapeTemplate escapes the backslashes and braces of the text, so that a template renders it as is.
+func escapeTemplate(text string) string {
+	if !strings.ContainsAny(text, `\{}`) {
+		return text
 	}
-
-	var modif Modifier
-	for _, m := range DefaultModifier() {
-		if m.Valid(modifier) {
-			modif = m
-			break
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		if isEscapable(text[i]) {
+			sb.WriteByte('\\')
 		}
+		sb.WriteByte(text[i])
+	}
+	return sb.String()
+}
+
+// isEmpty reports whether the value is nil, a nil pointer, map, slice or interface, or an empty string.
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
 	}
-	if modif == nil {
-		return nil, fmt.Errorf("invalid modifier: \"%s\"", modifier)
+	if s, ok := value.(string); ok {
+		return s == ""
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isEscapable reports whether the character may follow a backslash escape in a template.
+func isEscapable(c byte) bool {
+	return c == '\\' || c == '{' || c == '}'
+}
+
+// isLetter reports whether the character is an ASCII letter.
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// isAlnum reports whether the character is an ASCII letter or digit.
+func isAlnum(c byte) bool {
+	return isLetter(c) || ('0' <= c && c <= '9')
+}
+
+// skipSpace returns the index of the first non-whitespace character of s at or after i.
+func skipSpace(s string, i int) int {
+	for i < len(s) && strings.IndexByte(" \t\n\f\r", s[i]) >= 0 {
+		i++
 	}
-	return modif.Modify(fmt.Sprintf("%v", value), modifier, execModifier)
+	return i
 }
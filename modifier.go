@@ -1,6 +1,7 @@
 package curly
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"regexp"
@@ -14,7 +15,24 @@ var (
 	defaultModifiers []Modifier
 )
 
+// Regex patterns used by the modifiers, compiled once.
+var (
+	regexNumberValid     = regexp.MustCompile(`^\s*[\*/\+\-][\s\.\*/\+\-\(\)0-9]*?[0-9\)]\s*$`)
+	regexNumberGroup     = regexp.MustCompile(`\(\s*((\-\s*)?[0-9]+(\.[0-9]+)?)?\s*\)`)
+	regexNumberOperators = []*regexp.Regexp{
+		regexp.MustCompile(`\s*([\*/\+\-\(])\s*((\-\s*)?[0-9]+(\.[0-9]+)?)\s*([\*/])\s*((\-\s*)?[0-9]+(\.[0-9]+)?)`),
+		regexp.MustCompile(`\s*([\*/\+\-\(])\s*((\-\s*)?[0-9]+(\.[0-9]+)?)\s*([\+\-])\s*((\-\s*)?[0-9]+(\.[0-9]+)?)`),
+	}
+	regexStringValid  = regexp.MustCompile(`(?i)^(\s*(pre|post|sub|cut|flip|remove|delete|replace)\((.*?)\)\s*\|)+$`)
+	regexStringSyntax = regexp.MustCompile(`(?i)^\s*(pre|post|sub|cut|flip|remove|delete|replace)\((.*?)\)\s*$`)
+	regexFormatValid  = regexp.MustCompile(`(?i)^(\s*(money|left|center|right)\((.*?)\)\s*\|)+$`)
+	regexFormatSyntax = regexp.MustCompile(`(?i)^\s*(money|left|center|right)\((.*?)\)\s*$`)
+	regexMoneyArgs    = regexp.MustCompile(`^\s*([\.,]([0-9]*))\s*$`)
+	regexMoneyGroup   = regexp.MustCompile(`^([1-9][0-9]*?)([0-9]{3})($|[\.,])`)
+)
+
 // DefaultModifier manages the default list of modifiers.
+// It is used by the package-level functions and by engines created without WithModifiers.
 func DefaultModifier(modifiers ...Modifier) []Modifier {
 	if len(modifiers) != 0 {
 		mu.Lock()
@@ -52,8 +70,7 @@ type NumberModifier struct{}
 
 // Valid checks if the modifier is a valid numerical expression.
 func (m *NumberModifier) Valid(modifier string) bool {
-	reg := regexp.MustCompile(`^\s*[\*/\+\-][\s\.\*/\+\-\(\)0-9]*?[0-9\)]\s*$`)
-	return reg.MatchString(modifier) && charCount('(', modifier) == charCount(')', modifier)
+	return regexNumberValid.MatchString(modifier) && charCount('(', modifier) == charCount(')', modifier)
 }
 
 // Modify applies the numerical modifier to the given value.
@@ -61,25 +78,19 @@ func (m *NumberModifier) Modify(value string, modifier string, onfailed ...func(
 	modifier = value + modifier
 	syntax := "(" + modifier + ")"
 	if charCount('(', syntax) != charCount(')', syntax) {
-		return nil, fmt.Errorf("invalid expression: \"%s\"", modifier)
-	}
-	regParenthesis := regexp.MustCompile(`\(\s*((\-\s*)?[0-9]+(\.[0-9]+)?)?\s*\)`)
-	regOperator := []*regexp.Regexp{
-		regexp.MustCompile(`\s*([\*/\+\-\(])\s*((\-\s*)?[0-9]+(\.[0-9]+)?)\s*([\*/])\s*((\-\s*)?[0-9]+(\.[0-9]+)?)`),
-		regexp.MustCompile(`\s*([\*/\+\-\(])\s*((\-\s*)?[0-9]+(\.[0-9]+)?)\s*([\+\-])\s*((\-\s*)?[0-9]+(\.[0-9]+)?)`),
+		return nil, m.fail(value, modifier, nil)
 	}
-
 	for {
 		found := false
-		matches := regParenthesis.FindAllStringSubmatch(syntax, -1)
+		matches := regexNumberGroup.FindAllStringSubmatch(syntax, -1)
 		for _, match := range matches {
 			if match[1] == "" {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", match[0])
+				return nil, m.fail(value, match[0], nil)
 			}
 			syntax = strings.Replace(syntax, match[0], match[1], 1)
 			found = true
 		}
-		for _, reg := range regOperator {
+		for _, reg := range regexNumberOperators {
 			for {
 				matches := reg.FindAllStringSubmatch(syntax, -1)
 				if len(matches) == 0 {
@@ -88,15 +99,15 @@ func (m *NumberModifier) Modify(value string, modifier string, onfailed ...func(
 				for _, match := range matches {
 					val, err := calculate(match[2], match[5], match[6])
 					if err != nil {
-						return nil, err
+						return nil, m.fail(value, modifier, err)
 					}
-					if math.IsInf(val, 0) {
-						return nil, fmt.Errorf("division by zero: \"%s\"", modifier)
+					if math.IsInf(val, 0) || math.IsNaN(val) {
+						return nil, &ModifierError{Kind: ErrDivisionByZero, Expression: modifier}
 					}
 					if strings.Contains(match[2]+match[6], ".") {
 						syntax = strings.Replace(syntax, match[0], fmt.Sprintf("%s%f", match[1], val), 1)
 					} else {
-						syntax = strings.Replace(syntax, match[0], fmt.Sprintf("%s%v", match[1], val), 1)
+						syntax = strings.Replace(syntax, match[0], match[1]+strconv.FormatFloat(val, 'f', -1, 64), 1)
 					}
 					found = true
 				}
@@ -107,18 +118,37 @@ func (m *NumberModifier) Modify(value string, modifier string, onfailed ...func(
 		}
 	}
 	if charCount('(', syntax)+charCount(')', syntax) > 0 {
-		return nil, fmt.Errorf("invalid expression: \"%s\"", modifier)
+		return nil, m.fail(value, modifier, nil)
 	}
 	if strings.Contains(syntax, ".") {
-		if val, err := strconv.ParseFloat(syntax, 64); err == nil {
-			return val, nil
+		val, err := strconv.ParseFloat(syntax, 64)
+		if err != nil {
+			return nil, m.fail(value, modifier, err)
 		}
-		return nil, fmt.Errorf("invalid expression: \"%s\"", modifier)
-	}
-	if val, err := strconv.ParseInt(syntax, 10, 64); err == nil {
 		return val, nil
 	}
-	return nil, fmt.Errorf("invalid expression: \"%s\"", modifier)
+	val, err := strconv.ParseInt(syntax, 10, 64)
+	if err != nil {
+		return nil, m.fail(value, modifier, err)
+	}
+	return val, nil
+}
+
+// fail returns a ModifierError for the expression. The failure is reported as an invalid number when
+// the value is not a number or the result overflows, and as an invalid modifier otherwise.
+func (m *NumberModifier) fail(value string, expression string, err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return &ModifierError{Kind: ErrInvalidNumber, Expression: expression, Err: err}
+	}
+	kind := errorKind(err, nil)
+	if kind == nil {
+		err = nil
+		kind = ErrInvalidModifier
+		if _, e := numberOf(value); value != "" && e != nil {
+			kind = ErrInvalidNumber
+		}
+	}
+	return &ModifierError{Kind: kind, Expression: expression, Err: err}
 }
 
 // StringModifier implements Modifier for string transformations.
@@ -127,25 +157,23 @@ type StringModifier struct{}
 // Valid checks if the modifier is a valid string transformation expression.
 func (m *StringModifier) Valid(modifier string) bool {
 	modifier = strings.Trim(modifier, " |") + "|"
-	reg := regexp.MustCompile(`(?i)^(\s*(pre|post|sub|cut|flip|remove|delete|replace)\((.*?)\)\s*\|)+$`)
-	return reg.MatchString(modifier)
+	return regexStringValid.MatchString(modifier)
 }
 
 // Modify applies the string transformation modifier to the given value.
 func (m *StringModifier) Modify(value string, modifier string, onfailed ...func(value any, modifier string) (any, error)) (any, error) {
 	var result any = value
 	syntax := strings.Trim(modifier, " |")
-	reg := regexp.MustCompile(`(?i)^\s*(pre|post|sub|cut|flip|remove|delete|replace)\((.*?)\)\s*$`)
 	for _, expression := range stringSplit(syntax) {
 		value := fmt.Sprintf("%v", result)
-		match := reg.FindStringSubmatch(expression)
+		match := regexStringSyntax.FindStringSubmatch(expression)
 		if len(match) == 0 {
 			if len(onfailed) == 0 || onfailed[0] == nil {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			val, err := onfailed[0](value, expression)
 			if err != nil {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, err
 			}
 			result = val
 			continue
@@ -153,18 +181,18 @@ func (m *StringModifier) Modify(value string, modifier string, onfailed ...func(
 		switch match[1] {
 		case "pre":
 			if match[2] == "" {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			result = match[2] + value
 		case "post":
 			if match[2] == "" {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			result = value + match[2]
 		case "sub":
 			n, err := strconv.ParseInt(match[2], 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			if n < 0 {
 				n = int64(len(value)) + n
@@ -181,7 +209,7 @@ func (m *StringModifier) Modify(value string, modifier string, onfailed ...func(
 		case "cut":
 			n, err := strconv.ParseInt(match[2], 10, 64)
 			if err != nil || n == 0 {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			if n < 0 {
 				n = int64(len(value)) + n
@@ -199,7 +227,7 @@ func (m *StringModifier) Modify(value string, modifier string, onfailed ...func(
 			}
 		case "flip":
 			if match[2] != "" {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			flip := ""
 			for _, char := range value {
@@ -208,23 +236,23 @@ func (m *StringModifier) Modify(value string, modifier string, onfailed ...func(
 			result = flip
 		case "remove":
 			if match[2] == "" {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			result = strings.ReplaceAll(value, match[2], "")
 		case "delete":
 			if match[2] == "" {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			reg := regexp.MustCompile(`(?i)([` + regexp.QuoteMeta(match[2]) + `]+)`)
 			result = reg.ReplaceAllString(value, "")
 		case "replace":
 			args := strings.Split(match[2], ",")
 			if len(args) != 2 {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			result = strings.ReplaceAll(value, args[0], args[1])
 		default:
-			return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+			return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 		}
 	}
 	return result, nil
@@ -236,25 +264,23 @@ type FormatModifier struct{}
 // Valid checks if the modifier is a valid formatting expression.
 func (m *FormatModifier) Valid(modifier string) bool {
 	modifier = strings.Trim(modifier, " |") + "|"
-	reg := regexp.MustCompile(`(?i)^(\s*(money|left|center|right)\((.*?)\)\s*\|)+$`)
-	return reg.MatchString(modifier)
+	return regexFormatValid.MatchString(modifier)
 }
 
 // Modify applies the formatting modifier to the given value.
 func (m *FormatModifier) Modify(value string, modifier string, onfailed ...func(value any, modifier string) (any, error)) (any, error) {
 	var result any = value
 	syntax := strings.Trim(modifier, " |")
-	reg := regexp.MustCompile(`(?i)^\s*(money|left|center|right)\((.*?)\)\s*$`)
 	for _, expression := range stringSplit(syntax) {
 		value := fmt.Sprintf("%v", result)
-		match := reg.FindStringSubmatch(expression)
+		match := regexFormatSyntax.FindStringSubmatch(expression)
 		if len(match) == 0 {
 			if len(onfailed) == 0 || onfailed[0] == nil {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			val, err := onfailed[0](value, expression)
 			if err != nil {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, err
 			}
 			result = val
 			continue
@@ -264,10 +290,9 @@ func (m *FormatModifier) Modify(value string, modifier string, onfailed ...func(
 			dec := 0
 			sym := "."
 			if match[2] != "" {
-				reg := regexp.MustCompile(`^\s*([\.,]([0-9]*))\s*$`)
-				m := reg.FindStringSubmatch(match[2])
+				m := regexMoneyArgs.FindStringSubmatch(match[2])
 				if len(m) <= 0 {
-					return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+					return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 				}
 				sym = m[1][:1]
 				if m[2] != "" {
@@ -276,7 +301,7 @@ func (m *FormatModifier) Modify(value string, modifier string, onfailed ...func(
 			}
 			n, err := strconv.ParseFloat(value, 64)
 			if err != nil {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidNumber, Expression: expression}
 			}
 			val := fmt.Sprintf("%."+strconv.Itoa(dec)+"f", n)
 			if sym == "," {
@@ -285,15 +310,14 @@ func (m *FormatModifier) Modify(value string, modifier string, onfailed ...func(
 			} else {
 				sym = ","
 			}
-			reg := regexp.MustCompile(`^([1-9][0-9]*?)([0-9]{3})($|[\.,])`)
-			for reg.MatchString(val) {
-				val = reg.ReplaceAllString(val, "$1"+sym+"$2$3")
+			for regexMoneyGroup.MatchString(val) {
+				val = regexMoneyGroup.ReplaceAllString(val, "$1"+sym+"$2$3")
 			}
 			result = val
 		case "left":
 			n, err := strconv.ParseInt(match[2], 10, 64)
 			if err != nil || n <= 0 {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			if int(n) > len(value) {
 				result = value + strings.Repeat(" ", int(n)-len(value))
@@ -303,7 +327,7 @@ func (m *FormatModifier) Modify(value string, modifier string, onfailed ...func(
 		case "center":
 			n, err := strconv.ParseInt(match[2], 10, 64)
 			if err != nil || n <= 0 {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			if int(n) > len(value) {
 				half := (int(n) - len(value)) / 2
@@ -316,7 +340,7 @@ func (m *FormatModifier) Modify(value string, modifier string, onfailed ...func(
 		case "right":
 			n, err := strconv.ParseInt(match[2], 10, 64)
 			if err != nil || n <= 0 {
-				return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+				return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 			}
 			if int(n) > len(value) {
 				result = strings.Repeat(" ", int(n)-len(value)) + value
@@ -324,7 +348,7 @@ func (m *FormatModifier) Modify(value string, modifier string, onfailed ...func(
 				result = value[len(value)-int(n):]
 			}
 		default:
-			return nil, fmt.Errorf("invalid expression: \"%s\"", expression)
+			return nil, &ModifierError{Kind: ErrInvalidModifier, Expression: expression}
 		}
 	}
 	return result, nil
@@ -55,12 +55,12 @@ func TestModifier(t *testing.T) {
 			{"100", "+100.00", true, float64(200), nil},
 			{"100.00", "+100", true, float64(200), nil},
 			{"100.00", "+100.00", true, float64(200), nil},
-			{"100", "+100,00", false, nil, fmt.Errorf("invalid expression: \"%s%s\"", "100", "+100,00")},
-			{"100,00", "+100", true, nil, fmt.Errorf("invalid expression: \"%s%s\"", "100,00", "+100")},
-			{"100,00", "+100,00", false, nil, fmt.Errorf("invalid expression: \"%s%s\"", "100,00", "+100,00")},
-			{"100.00", "+100.00)", false, nil, fmt.Errorf("invalid expression: \"%s%s\"", "100.00", "+100.00)")},
-			{"100.00", "+100.00*/200", true, nil, fmt.Errorf("invalid expression: \"%s%s\"", "100.00", "+100.00*/200")},
-			{"100", " *-3 / 0 ", true, nil, fmt.Errorf("division by zero: \"%s%s\"", "100", " *-3 / 0 ")},
+			{"100", "+100,00", false, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "100" + "+100,00"}},
+			{"100,00", "+100", true, nil, &curly.ModifierError{Kind: curly.ErrInvalidNumber, Expression: "100,00" + "+100"}},
+			{"100,00", "+100,00", false, nil, &curly.ModifierError{Kind: curly.ErrInvalidNumber, Expression: "100,00" + "+100,00"}},
+			{"100.00", "+100.00)", false, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "100.00" + "+100.00)"}},
+			{"100.00", "+100.00*/200", true, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "100.00" + "+100.00*/200"}},
+			{"100", " *-3 / 0 ", true, nil, &curly.ModifierError{Kind: curly.ErrDivisionByZero, Expression: "100" + " *-3 / 0 "}},
 		},
 	}
 
@@ -77,18 +77,18 @@ func TestModifier(t *testing.T) {
 			{"abcdef", " | remove(cde) ", true, "abf", nil},
 			{"aabbccddeeff", " | delete(cde)", true, "aabbff", nil},
 			{"abcdefghi", "|replace(ghi,xyz)", true, "abcdefxyz", nil},
-			{"abcdef", "pre()", true, nil, fmt.Errorf("invalid expression: \"%s\"", "pre()")},
-			{"abcdef", "post()", true, nil, fmt.Errorf("invalid expression: \"%s\"", "post()")},
-			{"abcdef", "sub(x)", true, nil, fmt.Errorf("invalid expression: \"%s\"", "sub(x)")},
-			{"abcdef", "cut()", true, nil, fmt.Errorf("invalid expression: \"%s\"", "cut()")},
-			{"abcdef", "flip(11)", true, nil, fmt.Errorf("invalid expression: \"%s\"", "flip(11)")},
-			{"abcdef", "remove()", true, nil, fmt.Errorf("invalid expression: \"%s\"", "remove()")},
-			{"abcdef", "delete()", true, nil, fmt.Errorf("invalid expression: \"%s\"", "delete()")},
+			{"abcdef", "pre()", true, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "pre()"}},
+			{"abcdef", "post()", true, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "post()"}},
+			{"abcdef", "sub(x)", true, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "sub(x)"}},
+			{"abcdef", "cut()", true, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "cut()"}},
+			{"abcdef", "flip(11)", true, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "flip(11)"}},
+			{"abcdef", "remove()", true, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "remove()"}},
+			{"abcdef", "delete()", true, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "delete()"}},
 			{"abcdef", " |cut(30)", true, "", nil},
 			{"abcdef", " |cut(-30)", true, "", nil},
 			{"abcdef", " |sub(30)", true, "abcdef", nil},
 			{"abcdef", " |sub(-30)", true, "abcdef", nil},
-			{"abcdef", " |super()", false, nil, fmt.Errorf("invalid expression: \"%s\"", "super()")},
+			{"abcdef", " |super()", false, nil, &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "super()"}},
 		},
 	}
 
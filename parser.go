@@ -3,8 +3,10 @@ package curly
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Parser is an interface for parsing values based on identifiers.
@@ -14,6 +16,31 @@ type Parser interface {
 	Modify(value string, index int) any
 }
 
+// NewDateParser creates a new DateParser with the provided layouts and identifiers, parsing times in UTC.
+// The layouts use the date and time identifiers, such as "dd/mm/yyyy hh:nn" or "dd-mmm-yyyy"; without
+// layouts a set of common numeric and abbreviated layouts is used.
+//
+// A value matching a layout but naming no real date, such as "2024-02-30", is kept as a string, so the
+// parsed value is either a time.Time or the string found in the text.
+func NewDateParser(layouts []string, identifiers ...string) *DateParser {
+	return NewDateParserIn(time.UTC, layouts, identifiers...)
+}
+
+// NewDateParserIn creates a new DateParser like NewDateParser, parsing times in the given location.
+func NewDateParserIn(location *time.Location, layouts []string, identifiers ...string) *DateParser {
+	if len(layouts) == 0 {
+		layouts = defaultDateLayouts
+	}
+	p := &DateParser{
+		identifiers: identifiers,
+		location:    location,
+	}
+	for _, layout := range layouts {
+		p.layouts = append(p.layouts, compileDateLayout(layout))
+	}
+	return p
+}
+
 // NewMsisdnParser creates a new MsisdnParser with the provided country code, format, and identifiers.
 func NewMsisdnParser(country uint, format int, identifiers ...string) *MsisdnParser {
 	return &MsisdnParser{
@@ -139,3 +166,214 @@ func (p *StringParser) Modify(value string, index int) any {
 	}
 	return value
 }
+
+// DateParser parses dates and times into time.Time based on a list of layouts.
+type DateParser struct {
+	identifiers []string
+	layouts     []dateLayout
+	location    *time.Location
+}
+
+// Valid checks if the identifier is valid for date parsing.
+func (p *DateParser) Valid(identifier string) bool {
+	return ValidIdentifier(identifier, p.identifiers)
+}
+
+// Expressions returns one regex expression per layout.
+func (p *DateParser) Expressions() []string {
+	expressions := make([]string, len(p.layouts))
+	for i, layout := range p.layouts {
+		expressions[i] = layout.expression
+	}
+	return expressions
+}
+
+// Modify parses the value with the layout at the given index. The value is returned unchanged when it
+// is not a valid date.
+func (p *DateParser) Modify(value string, index int) any {
+	if index < 0 || index >= len(p.layouts) {
+		return value
+	}
+	if t, ok := p.layouts[index].parse(strings.TrimSpace(value), p.location); ok {
+		return t
+	}
+	return value
+}
+
+// defaultDateLayouts are the layouts of a DateParser created without layouts.
+var defaultDateLayouts = []string{
+	"dd/mm/yyyy hh:nn:ss", "dd/mm/yyyy hh:nn", "dd/mm/yyyy",
+	"yyyy-mm-dd hh:nn:ss", "yyyy-mm-dd", "dd-mmm-yyyy",
+}
+
+// dateMonths maps the lowercase English and Indonesian month names and abbreviations to their month.
+var dateMonths = func() map[string]time.Month {
+	months := map[string]time.Month{}
+	for _, locale := range []*Locale{LocaleEnglish, LocaleIndonesian} {
+		for i := 0; i < 12; i++ {
+			months[strings.ToLower(locale.Months[i])] = time.Month(i + 1)
+			months[strings.ToLower(locale.ShortMonths[i])] = time.Month(i + 1)
+		}
+	}
+	return months
+}()
+
+// dateTokens maps the identifiers recognised in a date layout to the regex expression of their value.
+var dateTokens = func() map[string]string {
+	names := make([]string, 0, len(dateMonths))
+	for name := range dateMonths {
+		names = append(names, name)
+	}
+	// Longest first, so that "jan" does not stop the match of "januari"
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	var days []string
+	for _, locale := range []*Locale{LocaleEnglish, LocaleIndonesian} {
+		days = append(days, locale.Days[:]...)
+		days = append(days, locale.ShortDays[:]...)
+	}
+	sort.Slice(days, func(i, j int) bool { return len(days[i]) > len(days[j]) })
+	return map[string]string{
+		"yyyy": `[0-9]{4}`,
+		"yy":   `[0-9]{2}`,
+		"mmmm": `(?i:` + strings.Join(names, "|") + `)`,
+		"mmm":  `(?i:` + strings.Join(names, "|") + `)`,
+		"mm":   `[0-9]{1,2}`,
+		"dddd": `(?i:` + strings.Join(days, "|") + `)`,
+		"ddd":  `(?i:` + strings.Join(days, "|") + `)`,
+		"dd":   `[0-9]{1,2}`,
+		"hh12": `[0-9]{1,2}`,
+		"hh":   `[0-9]{1,2}`,
+		"ampm": `(?i:am|pm)`,
+		"nn":   `[0-9]{2}`,
+		"ss":   `[0-9]{2}`,
+		"ms":   `[0-9]{3}`,
+	}
+}()
+
+// dateLayout is a date layout compiled into the expression given to patterns and the regular
+// expression capturing its fields.
+type dateLayout struct {
+	expression string
+	regexp     *regexp.Regexp
+	fields     []string
+}
+
+// compileDateLayout translates a date layout. Identifiers are matched with their value, text between
+// quotes and characters after a backslash are matched as is, and whitespace matches any whitespace.
+func compileDateLayout(layout string) dateLayout {
+	var expression, capture strings.Builder
+	var fields []string
+	literal := func(s string) {
+		expression.WriteString(regexp.QuoteMeta(s))
+		capture.WriteString(regexp.QuoteMeta(s))
+	}
+	for i := 0; i < len(layout); {
+		c := layout[i]
+		switch {
+		case c == '\\' && i+1 < len(layout):
+			literal(layout[i+1 : i+2])
+			i += 2
+			continue
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(layout[i+1:], c)
+			if end < 0 {
+				end = len(layout) - i - 1
+			}
+			literal(layout[i+1 : i+1+end])
+			i += end + 2
+			continue
+		case strings.IndexByte(" \t\n\f\r", c) >= 0:
+			i = skipSpace(layout, i)
+			expression.WriteString(`\s+`)
+			capture.WriteString(`\s+`)
+			continue
+		}
+		token := ""
+		for _, tok := range layoutTokens {
+			if _, ok := dateTokens[tok]; ok && strings.HasPrefix(layout[i:], tok) {
+				token = tok
+				break
+			}
+		}
+		if token == "" {
+			literal(layout[i : i+1])
+			i++
+			continue
+		}
+		expression.WriteString(dateTokens[token])
+		capture.WriteString("(" + dateTokens[token] + ")")
+		fields = append(fields, token)
+		i += len(token)
+	}
+	return dateLayout{
+		expression: expression.String(),
+		regexp:     regexp.MustCompile(`(?i)^` + capture.String() + `$`),
+		fields:     fields,
+	}
+}
+
+// parse reads the fields of the value and returns the time in the given location. Components missing
+// from the layout are zero, as with time.Parse.
+func (l dateLayout) parse(value string, location *time.Location) (time.Time, bool) {
+	match := l.regexp.FindStringSubmatch(value)
+	if match == nil {
+		return time.Time{}, false
+	}
+	year, month, day := 0, time.January, 1
+	hour, minute, second, nsec := 0, 0, 0, 0
+	pm, clock12 := false, false
+	for i, field := range l.fields {
+		text := match[i+1]
+		n, _ := strconv.Atoi(text)
+		switch field {
+		case "yyyy":
+			year = n
+		case "yy":
+			// Same pivot as time.Parse
+			year = 1900 + n
+			if n < 69 {
+				year += 100
+			}
+		case "mmmm", "mmm":
+			month = dateMonths[strings.ToLower(text)]
+		case "mm":
+			month = time.Month(n)
+		case "dd":
+			day = n
+		case "hh":
+			hour = n
+		case "hh12":
+			hour, clock12 = n, true
+		case "ampm":
+			pm = strings.EqualFold(text, "pm")
+		case "nn":
+			minute = n
+		case "ss":
+			second = n
+		case "ms":
+			nsec = n * int(time.Millisecond)
+		}
+	}
+	if clock12 {
+		if hour < 1 || hour > 12 {
+			return time.Time{}, false
+		}
+		hour %= 12
+		if pm {
+			hour += 12
+		}
+	}
+	if month < 1 || month > 12 || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, month, day, hour, minute, second, nsec, location)
+	if t.Day() != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/ceebydith/curly"
 	"github.com/stretchr/testify/require"
@@ -92,6 +93,22 @@ func TestParser(t *testing.T) {
 		},
 	}
 
+	testDate := parserTester{
+		parser: curly.NewDateParser([]string{"dd/mm/yyyy hh:nn", "yyyy-mm-dd", "dd-mmm-yyyy", "dd mmmm yy, hh12.nn ampm"}, "date", "paid"),
+		scenarios: []parserScenarioTest{
+			{"date", "06/12/2024 06:46", true, true, time.Date(2024, 12, 6, 6, 46, 0, 0, time.UTC)},
+			{"date", "2024-12-06", true, true, time.Date(2024, 12, 6, 0, 0, 0, 0, time.UTC)},
+			{"paid", "06-DES-2024", true, true, time.Date(2024, 12, 6, 0, 0, 0, 0, time.UTC)},
+			{"paid", "17-Agu-2024", true, true, time.Date(2024, 8, 17, 0, 0, 0, 0, time.UTC)},
+			{"paid", "01-MEI-2024", true, true, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+			{"paid", "31-oct-2024", true, true, time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC)},
+			{"paid", "06 Desember 24, 6.46 PM", true, true, time.Date(2024, 12, 6, 18, 46, 0, 0, time.UTC)},
+			{"date", "2024-02-30", true, true, "2024-02-30"},
+			{"date", "06/13/2024 06:46", true, true, "06/13/2024 06:46"},
+			{"time", "06/12/2024", false, true, nil},
+		},
+	}
+
 	t.Run("TestNumberParser", testNumber.Test)
 	t.Run("TestStringParser", testString.Test)
 	t.Run("TestStringNoTrimParser", testStringNoTrim.Test)
@@ -99,6 +116,7 @@ func TestParser(t *testing.T) {
 	t.Run("TestMsisdnPlusParser", testMsisdnPlus.Test)
 	t.Run("TestMsisdnZeroParser", testMsisdnZero.Test)
 	t.Run("TestMsisdnNoCodeParser", testMsisdnNoCode.Test)
+	t.Run("TestDateParser", testDate.Test)
 }
 
 type parserScenarioTest struct {
@@ -137,3 +155,16 @@ func (tester *parserTester) Test(t *testing.T) {
 		}
 	}
 }
+
+func TestDateParserPattern(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	parser := curly.NewDateParserIn(jakarta, nil, "paid", "due")
+
+	result, err := curly.Parse("TRX 1234 LUNAS 06/12/2024 06:46 JATUH TEMPO 20-DES-2024 STATUS: SUCCESS", []string{
+		"LUNAS {paid} JATUH",
+		"TEMPO {due} STATUS",
+	}, parser)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 12, 6, 6, 46, 0, 0, jakarta), result["paid"])
+	require.Equal(t, time.Date(2024, 12, 20, 0, 0, 0, 0, jakarta), result["due"])
+}
@@ -0,0 +1,312 @@
+package curly
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// PartialLoader provides the source text of the partials included by a template with {>name}.
+type PartialLoader interface {
+	Load(name string) (string, error)
+}
+
+// NewMapLoader creates a new MapLoader with the provided partials, keyed by name.
+func NewMapLoader(partials map[string]string) *MapLoader {
+	return &MapLoader{
+		partials: partials,
+	}
+}
+
+// MapLoader loads partials from a map of names to source texts.
+type MapLoader struct {
+	partials map[string]string
+}
+
+// Load returns the source text of the partial, or an error matching fs.ErrNotExist.
+func (l *MapLoader) Load(name string) (string, error) {
+	text, ok := l.partials[name]
+	if !ok {
+		return "", fmt.Errorf("partial \"%s\": %w", name, fs.ErrNotExist)
+	}
+	return text, nil
+}
+
+// NewFSLoader creates a new FSLoader reading the partials from the file system, the name of the file
+// being the name of the partial followed by the given extension, such as ".tmpl".
+func NewFSLoader(fsys fs.FS, extension string) *FSLoader {
+	return &FSLoader{
+		fsys:      fsys,
+		extension: extension,
+	}
+}
+
+// FSLoader loads partials from a file system such as os.DirFS or an embed.FS.
+type FSLoader struct {
+	fsys      fs.FS
+	extension string
+}
+
+// Load returns the content of the file of the partial. A name leaving the root of the file system, such
+// as ../secret, is an error matching fs.ErrInvalid.
+func (l *FSLoader) Load(name string) (string, error) {
+	path := name + l.extension
+	if !fs.ValidPath(path) {
+		return "", &fs.PathError{Op: "open", Path: path, Err: fs.ErrInvalid}
+	}
+	b, err := fs.ReadFile(l.fsys, path)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// partialNode is the inclusion of a partial such as {>header} or {>line label="PPJ" value=pln.ppj}.
+// The partial is loaded when the template is compiled and rendered with the parameters taking
+// precedence over the formatters of the template.
+type partialNode struct {
+	raw    string
+	offset int
+	name   string
+	params []param
+	text   string
+	nodes  []node
+	loaded bool
+}
+
+// param is a parameter of a partial, set to a literal or to the value of an identifier.
+type param struct {
+	name string
+	operand
+}
+
+func (n *partialNode) branches() [][]node {
+	return [][]node{n.nodes}
+}
+
+func (n *partialNode) render(w io.StringWriter, s *state) error {
+	values := make(map[string]any, len(n.params))
+	for _, prm := range n.params {
+		if prm.identifier == "" {
+			values[prm.name] = prm.literal
+			continue
+		}
+		value, _, found, err := s.lookup(prm.identifier, "")
+		if err != nil {
+			return n.fail(s, errorKind(err, ErrInvalidValue), prm.identifier, err)
+		}
+		if !found {
+			if s.engine.missing == MissingError {
+				return n.fail(s, ErrUnknownIdentifier, prm.identifier, nil)
+			}
+
+			// A later stage may provide the parameter
+			s.unresolve(prm.identifier)
+			if s.engine.missing == MissingKeep {
+				_, err = w.WriteString(n.raw)
+				return err
+			}
+
+			// The parameter stays bound, so that the name is not read from the formatters
+			values[prm.name] = ""
+			continue
+		}
+		values[prm.name] = value
+	}
+
+	inner := *s
+	inner.text, inner.partial = n.text, n.name
+	if len(values) != 0 {
+		inner.formatters = append([]Formatter{NewMapFormatter(values)}, s.formatters...)
+	}
+	for _, c := range n.nodes {
+		if err := c.render(w, &inner); err != nil {
+			return err
+		}
+	}
+	s.unresolved = inner.unresolved
+	return nil
+}
+
+// validate checks that a formatter provides the identifiers of the parameters, and the partial with its
+// parameters available.
+func (n *partialNode) validate(s *state) []error {
+	var errs []error
+	values := make(map[string]any, len(n.params))
+	for _, prm := range n.params {
+		if prm.identifier != "" && s.formatter(prm.identifier) == nil {
+			errs = append(errs, n.fail(s, ErrUnknownIdentifier, prm.identifier, nil))
+		}
+		values[prm.name] = nil
+	}
+
+	inner := *s
+	inner.text, inner.partial = n.text, n.name
+	inner.formatters = append([]Formatter{NewMapFormatter(values)}, s.formatters...)
+	return append(errs, validate(n.nodes, &inner)...)
+}
+
+// fields describes the identifiers of the parameters located in the source text of the template.
+func (n *partialNode) fields(text string) []Field {
+	var fields []Field
+	line, column := position(text, n.offset)
+	for _, prm := range n.params {
+		if prm.identifier != "" {
+			fields = append(fields, Field{Identifier: prm.identifier, Raw: n.raw, Offset: n.offset, Line: line, Column: column})
+		}
+	}
+	return fields
+}
+
+// fail returns a TemplateError of the given kind located at the inclusion.
+func (n *partialNode) fail(s *state, kind error, identifier string, err error) error {
+	e := s.fail(kind, n.offset, n.raw, err)
+	e.Identifier = identifier
+	return e
+}
+
+// scanPartial reads the inclusion of a partial such as {>header} or {> line label="PPJ" value=pln.ppj}
+// at the start of s and returns it with its length. The name of a partial is made of letters, digits
+// and "_", ".", "/" or "-". A parameter is set to a quoted text, an identifier, or else the literal text
+// up to the next space.
+func scanPartial(s string) (*partialNode, int, bool) {
+	if len(s) == 0 || s[0] != '{' {
+		return nil, 0, false
+	}
+	i := skipSpace(s, 1)
+	if i >= len(s) || s[i] != '>' {
+		return nil, 0, false
+	}
+	i = skipSpace(s, i+1)
+	start := i
+	for i < len(s) && (isAlnum(s[i]) || strings.IndexByte("_./-", s[i]) >= 0) {
+		i++
+	}
+	if i == start {
+		return nil, 0, false
+	}
+	n := &partialNode{name: s[start:i]}
+
+	for {
+		j := skipSpace(s, i)
+		if j >= len(s) {
+			return nil, 0, false
+		}
+		if s[j] == '}' {
+			return n, j + 1, true
+		}
+		if j == i {
+			return nil, 0, false
+		}
+
+		// name=value
+		i = scanIdentifier(s, j)
+		if i == j || i >= len(s) || s[i] != '=' {
+			return nil, 0, false
+		}
+		prm := param{name: s[j:i]}
+		for _, p := range n.params {
+			if strings.EqualFold(p.name, prm.name) {
+				return nil, 0, false
+			}
+		}
+		i++
+
+		var ok bool
+		prm.operand, i, ok = scanParamValue(s, i)
+		if !ok {
+			return nil, 0, false
+		}
+		n.params = append(n.params, prm)
+	}
+}
+
+// scanParamValue reads the value of a parameter at i and returns it with its end.
+func scanParamValue(s string, i int) (operand, int, bool) {
+	if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+		var sb strings.Builder
+		for j := i + 1; j < len(s); j++ {
+			switch c := s[j]; {
+			case c == '\\' && j+1 < len(s):
+				sb.WriteByte(s[j+1])
+				j++
+			case c == s[i]:
+				return operand{literal: sb.String()}, j + 1, true
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return operand{}, i, false
+	}
+
+	j := i
+	for j < len(s) && strings.IndexByte(" \t\n\f\r{}\"'", s[j]) < 0 {
+		j++
+	}
+	if j == i {
+		return operand{}, i, false
+	}
+	if scanIdentifier(s[i:j], 0) == j-i {
+		return operand{identifier: s[i:j]}, j, true
+	}
+	return operand{literal: s[i:j]}, j, true
+}
+
+// includePartials loads the partials included by the nodes parsed from text, and the partials they
+// include in turn. The stack holds the names of the partials being loaded, to detect cycles, and loaded
+// the partials already loaded by name, so that each partial is loaded and parsed once.
+func (e *Engine) includePartials(text string, nodes []node, stack []string, loaded map[string]*partialNode) error {
+	var err error
+	walk(nodes, func(n node) {
+		p, ok := n.(*partialNode)
+		if !ok || p.loaded || err != nil {
+			return
+		}
+		err = e.loadPartial(text, p, stack, loaded)
+	})
+	return err
+}
+
+// loadPartial loads and parses the partial included by the node.
+func (e *Engine) loadPartial(text string, p *partialNode, stack []string, loaded map[string]*partialNode) error {
+	if l, ok := loaded[p.name]; ok {
+		p.text, p.nodes, p.loaded = l.text, l.nodes, true
+		return nil
+	}
+
+	fail := func(err error) error {
+		te := newTemplateError(ErrInvalidExpression, text, p.offset, p.raw, err)
+		if len(stack) != 0 {
+			te.Partial = stack[len(stack)-1]
+		}
+		return te
+	}
+
+	for i, name := range stack {
+		if name == p.name {
+			return fail(fmt.Errorf("partial cycle: %s > %s", strings.Join(stack[i:], " > "), p.name))
+		}
+	}
+	if e.partials == nil {
+		return fail(errors.New("no partial loader"))
+	}
+	source, err := e.partials.Load(p.name)
+	if err != nil {
+		return fail(err)
+	}
+	nodes, err := parseTemplate(source)
+	if err != nil {
+		if te, ok := err.(*TemplateError); ok {
+			te.Partial = p.name
+		}
+		return err
+	}
+	p.text, p.nodes, p.loaded = source, nodes, true
+	if err := e.includePartials(source, nodes, append(stack[:len(stack):len(stack)], p.name), loaded); err != nil {
+		return err
+	}
+	loaded[p.name] = p
+	return nil
+}
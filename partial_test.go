@@ -0,0 +1,121 @@
+package curly_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ceebydith/curly"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartial(t *testing.T) {
+	loader := curly.NewMapLoader(map[string]string{
+		"header": "{store|center(20)}\n",
+		"line":   "{label|left(8)}: {value|money(,)|right(10)}\n",
+		"footer": "{#if paid}LUNAS{#else}BELUM LUNAS{/if}",
+		"items":  "{#each items}{>line label=name value=price}{/each}",
+		"nested": "[{>leaf}]",
+		"leaf":   "{store}",
+		"loop":   "{>loop2}",
+		"loop2":  "x{>loop}",
+		"broken": "a\n{store",
+		"fail":   "{nothing}",
+	})
+
+	testPartial := templateTester{
+		engine: curly.NewEngine(curly.WithPartials(loader)),
+		formatters: []curly.Formatter{curly.NewMapFormatter(map[string]any{
+			"store": "TOKO ABC",
+			"paid":  true,
+			"ppj":   1819,
+			"items": []any{
+				map[string]any{"name": "Token", "price": 20000},
+				map[string]any{"name": "Admin", "price": 2500},
+			},
+		})},
+		scenarios: []templateScenarioTest{
+			{"{>header}", "      TOKO ABC      \n", nil},
+			{"{ > header }{>footer}", "      TOKO ABC      \nLUNAS", nil},
+			{"{>line label=\"PPJ\" value=ppj}", "PPJ     :      1.819\n", nil},
+			{"{>line label='Fee \\'X\\'' value=2500}", "Fee 'X' :      2.500\n", nil},
+			{"{>items}", "Token   :     20.000\nAdmin   :      2.500\n", nil},
+			{"{>nested}{>nested}", "[TOKO ABC][TOKO ABC]", nil},
+			{"{>leaf store=\"TOKO XYZ\"}/{store}", "TOKO XYZ/TOKO ABC", nil},
+			{"\\{>header\\}", "{>header}", nil},
+			{"{>loop}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{>loop}", Offset: 1, Line: 1, Column: 2, Partial: "loop2",
+				Err: errors.New("partial cycle: loop > loop2 > loop"),
+			}},
+			{"{>broken}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{store", Offset: 2, Line: 2, Column: 1, Partial: "broken",
+			}},
+			{"Total\n{>fail}", "", &curly.TemplateError{
+				Kind: curly.ErrUnknownIdentifier, Text: "{nothing}", Offset: 0, Line: 1, Column: 1, Identifier: "nothing", Partial: "fail",
+			}},
+			{"{>line label=name value=ppj}", "", &curly.TemplateError{
+				Kind: curly.ErrUnknownIdentifier, Text: "{>line label=name value=ppj}", Offset: 0, Line: 1, Column: 1, Identifier: "name",
+			}},
+			{"{>line label=}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{>line label=}", Offset: 0, Line: 1, Column: 1,
+			}},
+		},
+	}
+
+	t.Run("TestPartial", testPartial.Test)
+
+	_, err := testPartial.engine.Compile("{>missing}")
+	require.ErrorIs(t, err, curly.ErrInvalidExpression)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	require.EqualError(t, err, "invalid expression: \"{>missing}\" at line 1, column 1: partial \"missing\": file does not exist")
+
+	_, err = curly.Compile("{>header}")
+	require.EqualError(t, err, "invalid expression: \"{>header}\" at line 1, column 1: no partial loader")
+
+	_, err = testPartial.engine.Format("Total\n{>fail}")
+	require.EqualError(t, err, "unknown identifier: \"{nothing}\" at line 1, column 1 of partial \"fail\"")
+
+	// A missing parameter is empty inside the partial rather than read from the formatters
+	lenient := curly.NewEngine(curly.WithPartials(curly.NewMapLoader(map[string]string{"line": "[{value}]"})), curly.WithMissing(curly.MissingEmpty))
+	text, unresolved, err := lenient.FormatUnresolved("{>line value=nope}", curly.NewMapFormatter(map[string]any{"value": "GLOBAL"}))
+	require.NoError(t, err)
+	require.Equal(t, "[]", text)
+	require.Equal(t, []string{"nope"}, unresolved)
+}
+
+func TestPartialFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"receipt/header.tmpl": {Data: []byte("== {title} ==\n")},
+		"receipt/body.tmpl":   {Data: []byte("{>receipt/header title=\"STRUK\"}Nama: {name|left(10)}|")},
+	}
+	engine := curly.NewEngine(curly.WithPartials(curly.NewFSLoader(fsys, ".tmpl")))
+	data := curly.NewMapFormatter(map[string]any{"name": "John"})
+
+	text, err := engine.Format("{>receipt/body}", data)
+	require.NoError(t, err)
+	require.Equal(t, "== STRUK ==\nNama: John      |", text)
+
+	_, err = engine.Compile("{>../secret}")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+
+	tmpl, err := engine.Compile("{>receipt/body}{>receipt/header title=caption}")
+	require.NoError(t, err)
+	require.Equal(t, []curly.Field{
+		{Identifier: "title", Raw: "{title}", Offset: 3, Line: 1, Column: 4, Partial: "receipt/header"},
+		{Identifier: "name", Modifier: "|left(10)", Raw: "{name|left(10)}", Offset: 37, Line: 1, Column: 38, Partial: "receipt/body"},
+		{Identifier: "caption", Raw: "{>receipt/header title=caption}", Offset: 15, Line: 1, Column: 16},
+		{Identifier: "title", Raw: "{title}", Offset: 3, Line: 1, Column: 4, Partial: "receipt/header"},
+	}, tmpl.Fields())
+
+	require.NoError(t, tmpl.Validate(curly.NewMapFormatter(map[string]any{"name": "", "caption": ""})))
+	err = tmpl.Validate(data)
+	require.ErrorIs(t, err, curly.ErrUnknownIdentifier)
+	require.EqualError(t, err, "unknown identifier: \"{>receipt/header title=caption}\" at line 1, column 16")
+
+	staged := curly.NewEngine(curly.WithPartials(curly.NewFSLoader(fsys, ".tmpl")), curly.WithMissing(curly.MissingKeep))
+	text, unresolved, err := staged.FormatUnresolved("{>receipt/body}", curly.NewMapFormatter(map[string]any{}))
+	require.NoError(t, err)
+	require.Equal(t, "== STRUK ==\nNama: {name|left(10)}|", text)
+	require.Equal(t, []string{"name"}, unresolved)
+}
@@ -0,0 +1,278 @@
+package curly
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Pattern is a parse expression whose segments have been analysed and compiled into regular
+// expressions once, so that it can be matched against many texts.
+type Pattern struct {
+	engine   *Engine
+	segments []*segment
+}
+
+// segment is a single compiled part of a parse expression.
+type segment struct {
+	index      int
+	expression string
+	offset     int
+	identifier string
+	modifier   string
+	parser     Parser
+	regexps    []*regexp.Regexp
+}
+
+// CompilePattern analyses the parse expression, binds every identifier to its parser and returns a
+// Pattern ready to be matched.
+func CompilePattern[T string | []string](expression T, parsers ...Parser) (*Pattern, error) {
+	expressions := stringSplit(expression)
+	if expressions == nil {
+		return nil, fmt.Errorf("invalid expression type: %T", expression)
+	}
+	return defaultEngine.CompilePattern(expressions, parsers...)
+}
+
+// MustCompilePattern is like CompilePattern but panics if the expression cannot be compiled.
+func MustCompilePattern[T string | []string](expression T, parsers ...Parser) *Pattern {
+	p, err := CompilePattern(expression, parsers...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Capture describes a placeholder of a parse expression.
+type Capture struct {
+	Identifier string // identifier the captured value is stored under
+	Modifier   string // modifier applied to the captured value
+	Parser     Parser // parser bound to the identifier
+	Segment    int    // index of the segment in the expression
+	Offset     int    // byte offset of the placeholder in the segment
+}
+
+// Captures returns the placeholders of the pattern in order of appearance.
+func (p *Pattern) Captures() []Capture {
+	var captures []Capture
+	for _, seg := range p.segments {
+		if seg.parser == nil {
+			continue
+		}
+		captures = append(captures, Capture{
+			Identifier: seg.identifier,
+			Modifier:   seg.modifier,
+			Parser:     seg.parser,
+			Segment:    seg.index,
+			Offset:     seg.offset,
+		})
+	}
+	return captures
+}
+
+// Match extracts data from text using the compiled segments of the pattern.
+func (p *Pattern) Match(text string) (map[string]any, error) {
+	result := map[string]any{}
+	for _, seg := range p.segments {
+		if seg.parser == nil {
+			if !seg.regexps[0].MatchString(text) {
+				return nil, seg.fail(ErrNoMatch, nil)
+			}
+			continue
+		}
+
+		parsed := false
+		for i, reg := range seg.regexps {
+			match := reg.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			value := seg.parser.Modify(match[1], i)
+
+			// Apply the modifier if present
+			value, err := p.engine.execModifier(value, seg.modifier)
+			if err != nil {
+				return nil, seg.fail(errorKind(err, ErrInvalidModifier), err)
+			}
+			result[seg.identifier] = value
+			parsed = true
+			break
+		}
+		if !parsed {
+			return nil, seg.fail(ErrNoMatch, nil)
+		}
+	}
+	return result, nil
+}
+
+// fail returns a PatternError of the given kind located at the segment.
+func (seg *segment) fail(kind error, err error) error {
+	return &PatternError{
+		Kind:       kind,
+		Segment:    seg.index,
+		Expression: seg.expression,
+		Offset:     seg.offset,
+		Identifier: seg.identifier,
+		Modifier:   seg.modifier,
+		Err:        err,
+	}
+}
+
+// compileSegment translates a single parse expression into its regular expressions.
+// Literal text is matched as is with any run of whitespace matching one or more spaces,
+// {num}, {alpha}, {alphanum} and {any} (optionally with a length such as {num:11}) match classes of characters,
+// "#" and "@" match a digit and a letter, and at most one placeholder captures the value for its parser.
+func compileSegment(index int, expression string, parsers []Parser) (*segment, error) {
+	seg := &segment{
+		index:      index,
+		expression: expression,
+	}
+	var sb strings.Builder
+	var before string
+	var atEnd bool
+
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == '\\' && i+1 < len(expression) && strings.IndexByte(`{}#@`, expression[i+1]) >= 0:
+			sb.WriteString(regexp.QuoteMeta(expression[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '{':
+			if class, n, ok := scanClass(expression[i:]); ok {
+				sb.WriteString(class)
+				i += n
+				continue
+			}
+			if identifier, modifier, n, ok := scanPlaceholder(expression[i:]); ok {
+				if seg.identifier != "" {
+					return nil, &PatternError{
+						Kind:       ErrInvalidExpression,
+						Segment:    index,
+						Expression: expression,
+						Offset:     i,
+						Identifier: identifier,
+						Modifier:   modifier,
+						Err:        errors.New("multiple identifier"),
+					}
+				}
+				seg.offset = i
+				seg.identifier = identifier
+				seg.modifier = modifier
+				before = sb.String()
+				sb.Reset()
+				atEnd = i+n == len(expression)
+				i += n
+				continue
+			}
+		case c == '#' || c == '@':
+			n := 1
+			for i+n < len(expression) && expression[i+n] == c {
+				n++
+			}
+			class := "[0-9]"
+			if c == '@' {
+				class = "[a-z]"
+			}
+			if n > 1 {
+				class += fmt.Sprintf("{%d}", n)
+			}
+			sb.WriteString(class)
+			i += n
+			continue
+		case strings.IndexByte(" \t\n\f\r", c) >= 0:
+			i = skipSpace(expression, i)
+			sb.WriteString(`\s+`)
+			continue
+		}
+		sb.WriteString(regexp.QuoteMeta(expression[i : i+1]))
+		i++
+	}
+
+	// Without identifier the segment only has to match
+	if seg.identifier == "" {
+		reg, err := regexp.Compile(`(?i)` + sb.String())
+		if err != nil {
+			return nil, seg.fail(ErrInvalidExpression, err)
+		}
+		seg.regexps = []*regexp.Regexp{reg}
+		return seg, nil
+	}
+
+	for _, p := range parsers {
+		if p.Valid(seg.identifier) {
+			seg.parser = p
+			break
+		}
+	}
+	if seg.parser == nil {
+		return nil, seg.fail(ErrUnknownIdentifier, nil)
+	}
+
+	// One regex per parser expression, capturing the value lazily unless it ends the segment
+	for _, regex := range seg.parser.Expressions() {
+		if atEnd {
+			regex = regex + "$"
+		} else {
+			regex = regex + "?"
+		}
+		regex = regexSpace.ReplaceAllString(regex, `\s+`)
+		reg, err := regexp.Compile(`(?i)` + before + "(" + regex + ")" + sb.String())
+		if err != nil {
+			return nil, seg.fail(ErrInvalidExpression, err)
+		}
+		seg.regexps = append(seg.regexps, reg)
+	}
+	return seg, nil
+}
+
+// regexSpace matches a run of whitespace in a parser expression.
+var regexSpace = regexp.MustCompile(`\s+`)
+
+// scanClass reads a character class placeholder such as {num}, {alpha:3} or {any} at the start of s
+// and returns its regular expression and the length of the placeholder.
+func scanClass(s string) (class string, n int, ok bool) {
+	i := skipSpace(s, 1)
+	start := i
+	for i < len(s) && isLetter(s[i]) {
+		i++
+	}
+	name := strings.ToLower(s[start:i])
+	i = skipSpace(s, i)
+
+	count := ""
+	if i < len(s) && s[i] == ':' {
+		i = skipSpace(s, i+1)
+		start = i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		num, err := strconv.Atoi(s[start:i])
+		if err != nil || s[start] == '0' {
+			return "", 0, false
+		}
+		count = fmt.Sprintf("{%d}", num)
+		i = skipSpace(s, i)
+	} else if name == "any" {
+		count = "*?"
+	} else {
+		count = "+?"
+	}
+	if i >= len(s) || s[i] != '}' {
+		return "", 0, false
+	}
+
+	switch name {
+	case "num":
+		return "[0-9]" + count, i + 1, true
+	case "alpha":
+		return "[a-z\\s]" + count, i + 1, true
+	case "alphanum":
+		return "[a-z0-9\\s]" + count, i + 1, true
+	case "any":
+		return "." + count, i + 1, true
+	}
+	return "", 0, false
+}
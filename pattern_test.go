@@ -0,0 +1,123 @@
+package curly_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ceebydith/curly"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPattern(t *testing.T) {
+	testPattern := patternTester{
+		pattern: curly.MustCompilePattern(
+			"TRX {id},|ke {dest} Harga|Harga {charge} ke|status:SUCCESS|### ({code})|SaldoAkhir {balance}",
+			curly.NewNumberParser("charge", "balance"),
+		),
+		scenarios: []patternScenarioTest{
+			{
+				"TRX 2189566, PLN 20000 (507) ke 133312626789 Harga 20.075 ke 133312626789 status:SUCCESS SaldoAkhir 2905071",
+				map[string]any{"id": "2189566", "dest": "133312626789", "charge": int64(20075), "code": "507", "balance": int64(2905071)},
+				nil,
+			},
+			{
+				"TRX 2189567, PLN 50000 (508) ke 133312626790 Harga 50,075.50 ke 133312626790 status:SUCCESS SaldoAkhir 2854996",
+				map[string]any{"id": "2189567", "dest": "133312626790", "charge": float64(50075.50), "code": "508", "balance": int64(2854996)},
+				nil,
+			},
+			{
+				"TRX 2189568, PLN 20000 (507) ke 133312626789 Harga 20075 ke 133312626789 status:FAILED SaldoAkhir 2905071",
+				nil,
+				&curly.PatternError{Kind: curly.ErrNoMatch, Segment: 3, Expression: "status:SUCCESS"},
+			},
+		},
+	}
+
+	t.Run("TestPattern", testPattern.Test)
+}
+
+func TestCompilePattern(t *testing.T) {
+	_, err := curly.CompilePattern("name {first} {last}")
+	require.ErrorIs(t, err, curly.ErrInvalidExpression)
+	require.EqualError(t, err, "invalid expression: \"name {first} {last}\" at segment 0, offset 13: multiple identifier")
+
+	_, err = curly.CompilePattern([]string{"code {code}"}, &curly.NumberParser{}, curly.NewMsisdnParser(0, 0, "code"))
+	require.NoError(t, err)
+
+	p, err := curly.CompilePattern([]string{"price {num:3}.{price} ", "\\#{index} ", "{alpha} @@@"}, curly.NewNumberParser("price", "index"))
+	require.NoError(t, err)
+	data, err := p.Match("#12 price 100.250 abc XYZ")
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"price": int64(250), "index": int64(12)}, data)
+}
+
+func BenchmarkParse(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := curly.Parse(benchmarkResponse, benchmarkExpression, curly.NewNumberParser("denom", "charge", "balance")); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPatternMatch(b *testing.B) {
+	p := curly.MustCompilePattern(benchmarkExpression, curly.NewNumberParser("denom", "charge", "balance"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Match(benchmarkResponse); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var benchmarkResponse = `TRX 2189566, PLN Prepaid 20000 (507) ke 133312626789 Harga 20075 ke 133312626789 (MBOK DARMI               ) status:SUCCESSFUL SaldoAwal 2925146, SaldoAkhir 2905071`
+
+var benchmarkExpression = []string{
+	"TRX {id},",
+	"TRX {num}, {product} {num}",
+	"TRX {num}, {alpha} {denom} ",
+	"ke {dest} Harga",
+	"Harga {charge} ke",
+	"ke {alphanum} ({name})",
+	"status:SUCCESSFUL",
+	"SaldoAkhir {balance}",
+}
+
+type patternScenarioTest struct {
+	text        string
+	expectMatch map[string]any
+	expectError error
+}
+
+type patternTester struct {
+	pattern   *curly.Pattern
+	scenarios []patternScenarioTest
+}
+
+func (tester *patternTester) Test(t *testing.T) {
+	for i, scenario := range tester.scenarios {
+		msg := fmt.Sprintf("#%d %s", i, scenario.text)
+		match, err := tester.pattern.Match(scenario.text)
+		require.Equal(t, scenario.expectMatch, match, "Match "+msg)
+		require.Equal(t, scenario.expectError, err, "Match "+msg)
+	}
+}
+
+func TestPatternCaptures(t *testing.T) {
+	number := curly.NewNumberParser("charge")
+	captures, err := curly.Captures("TRX {id},|Harga {charge/100} ke|status:SUCCESS", number)
+	require.NoError(t, err)
+	require.Len(t, captures, 2)
+
+	require.Equal(t, "id", captures[0].Identifier)
+	require.Equal(t, 0, captures[0].Segment)
+	require.Equal(t, 4, captures[0].Offset)
+	require.IsType(t, &curly.StringParser{}, captures[0].Parser)
+
+	require.Equal(t, "charge", captures[1].Identifier)
+	require.Equal(t, "/100", captures[1].Modifier)
+	require.Equal(t, 1, captures[1].Segment)
+	require.Same(t, number, captures[1].Parser)
+
+	_, err = curly.Captures([]string{"{a} {b}"})
+	require.ErrorIs(t, err, curly.ErrInvalidExpression)
+}
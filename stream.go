@@ -0,0 +1,232 @@
+package curly
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// streamChunk is the amount of text read from a stream at once, and rendered once available.
+	streamChunk = 32 << 10
+	// streamPlaceholder is the length beyond which an unterminated placeholder of a stream is an error.
+	streamPlaceholder = 64 << 10
+)
+
+// FormatStream reads the template from src and writes the formatted text to dst as it goes.
+// See Engine.FormatStream.
+func FormatStream(dst io.Writer, src io.Reader, formatters ...Formatter) error {
+	return defaultEngine.FormatStream(dst, src, formatters...)
+}
+
+// FormatStream is like Format but reads the template from src and writes the formatted text to dst as
+// it goes, so that large documents are rendered with bounded memory. The text is rendered a part at a
+// time, cut between placeholders; a section is held in memory up to its closing tag. Errors are located
+// in the whole stream, and on error part of the output may already have been written.
+func (e *Engine) FormatStream(dst io.Writer, src io.Reader, formatters ...Formatter) error {
+	bw := bufio.NewWriter(dst)
+	st := &stream{
+		engine:   e,
+		src:      src,
+		state:    newState(context.Background(), e, formatters),
+		partials: map[string]*partialNode{},
+	}
+	if err := st.run(bw); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// stream renders a template read from a reader, a part at a time.
+type stream struct {
+	engine *Engine
+	src    io.Reader
+	state  *state
+	buf    []byte // text read and not rendered yet, reused across reads
+	eof    bool
+
+	// Partials loaded for the whole stream, by name
+	partials map[string]*partialNode
+
+	// Location of the start of buf in the whole stream
+	offset int
+	line   int
+	column int
+}
+
+// run reads and renders the whole stream.
+func (st *stream) run(w io.StringWriter) error {
+	pos, cut, depth := 0, 0, 0
+	plain, tagged := false, false // tagged tells that only blanks follow a tag on the line
+	for {
+		// more tells that the buffer ends in the middle of a placeholder or an escape sequence
+		more := false
+	scan:
+		for pos < len(st.buf) {
+			c := st.buf[pos]
+			switch {
+			case c == '\\':
+				if pos+1 >= len(st.buf) && !st.eof {
+					more = true
+					break scan
+				}
+				pos++
+				if pos < len(st.buf) && isEscapable(st.buf[pos]) {
+					pos++
+				}
+				plain, tagged = false, false
+			case c == '{':
+				tag, n, ok := st.brace(pos)
+				if !ok && !st.eof && len(st.buf)-pos < streamPlaceholder {
+					more = true
+					break scan
+				}
+				if !ok {
+					// Rendering reports the invalid placeholder
+					n = 1
+				}
+
+				// The part may end before a placeholder, unless a tag followed by blanks only would then
+				// seem to stand alone
+				if ok && tag == nil && depth == 0 && !tagged {
+					cut = pos
+				}
+				tagged = tag != nil
+				if tag != nil && tag.opening() {
+					depth++
+				} else if tag != nil && strings.HasPrefix(tag.name, "/") && depth > 0 {
+					depth--
+				}
+				pos += n
+				plain = false
+			case c == '\n':
+				pos++
+				if depth == 0 {
+					cut = pos
+				}
+				plain, tagged = false, false
+			default:
+				// The part may end between two plain characters, as no tag before or after stands alone
+				if isPlain(c) && plain && depth == 0 {
+					cut = pos
+				}
+				plain = isPlain(c)
+				tagged = tagged && (c == ' ' || c == '\t' || c == '\r')
+				pos++
+			}
+			if cut >= streamChunk {
+				if err := st.render(w, cut); err != nil {
+					return err
+				}
+				pos, cut = pos-cut, 0
+			}
+		}
+
+		if !more && st.eof {
+			return st.render(w, len(st.buf))
+		}
+		if err := st.read(); err != nil {
+			return err
+		}
+	}
+}
+
+// read appends the next part of the stream to the buffer.
+func (st *stream) read() error {
+	st.buf = slices.Grow(st.buf, streamChunk)
+	n, err := io.ReadFull(st.src, st.buf[len(st.buf):len(st.buf)+streamChunk])
+	st.buf = st.buf[:len(st.buf)+n]
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		st.eof = true
+		return nil
+	}
+	return err
+}
+
+// render renders the first n bytes of the buffer and drops them.
+func (st *stream) render(w io.StringWriter, n int) error {
+	if n == 0 {
+		return nil
+	}
+	text := string(st.buf[:n])
+	nodes, err := parseTemplate(text)
+	if err == nil {
+		err = st.engine.includePartials(text, nodes, nil, st.partials)
+	}
+	st.state.text = text
+	if err == nil {
+		st.state.prefetch(nodes)
+	}
+	for _, c := range nodes {
+		if err != nil {
+			break
+		}
+		err = c.render(w, st.state)
+	}
+	if err != nil {
+		return st.locate(err)
+	}
+
+	st.buf = st.buf[:copy(st.buf, st.buf[n:])]
+	st.offset += n
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		st.line += strings.Count(text, "\n")
+		st.column = utf8.RuneCountInString(text[i+1:])
+	} else {
+		st.column += utf8.RuneCountInString(text)
+	}
+	return nil
+}
+
+// locate moves the location of an error found in the buffer to the whole stream.
+func (st *stream) locate(err error) error {
+	var e *TemplateError
+	if !errors.As(err, &e) || e.Partial != "" {
+		return err
+	}
+	if e.Line == 1 {
+		e.Column += st.column
+	}
+	e.Offset += st.offset
+	e.Line += st.line
+	return err
+}
+
+// brace reads the placeholder, tag or partial inclusion at pos in the buffer, converting no more of the
+// buffer to a string than it needs.
+func (st *stream) brace(pos int) (*blockTag, int, bool) {
+	for size := 256; ; size *= 2 {
+		end := min(pos+size, len(st.buf))
+		tag, n, ok := scanBrace(string(st.buf[pos:end]))
+		if ok || end == len(st.buf) || size >= streamPlaceholder {
+			return tag, n, ok
+		}
+	}
+}
+
+// scanBrace reads the placeholder, tag or partial inclusion at the start of s and returns its length,
+// with the tag when it is one.
+func scanBrace(s string) (*blockTag, int, bool) {
+	if _, n, ok := scanPartial(s); ok {
+		return nil, n, true
+	}
+	if tag, n, ok := scanTag(s); ok {
+		return tag, n, true
+	}
+	if _, _, n, ok := scanPlaceholder(s); ok {
+		return nil, n, true
+	}
+	if _, _, _, n, ok := scanFallback(s); ok {
+		return nil, n, true
+	}
+	return nil, 0, false
+}
+
+// isPlain reports whether the character is printable ASCII text with no meaning in a template.
+func isPlain(c byte) bool {
+	return c > ' ' && c < 0x7f && !isEscapable(c)
+}
@@ -0,0 +1,138 @@
+package curly_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/ceebydith/curly"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatStream(t *testing.T) {
+	formatter := curly.NewMapFormatter(map[string]any{
+		"store": "TOKO ABC",
+		"paid":  true,
+		"items": []any{
+			map[string]any{"name": "Token PLN 20K", "price": 20000},
+			map[string]any{"name": "Admin", "price": 2500},
+		},
+	})
+	receipt := curly.NewMapFormatter(benchmarkData)
+
+	var sb strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&sb, "Struk #%d {store|center(20)} \\{escaped\\}\n", i)
+		sb.WriteString("{#if paid}\nLUNAS\n{#else}\nBELUM LUNAS\n{/if}\n")
+		sb.WriteString("{#each items}\n- {name|left(15)}{price|money(,)|right(8)}\n{/each}\n")
+		sb.WriteString(benchmarkReceipt)
+		sb.WriteString("\n")
+	}
+	document := sb.String()
+	line := strings.Repeat("{store|left(10)}/{detail ?? \"}\"}/αβγ ", 5000)
+	spaced := strings.Repeat("{store} {paid} {detail ?? \""+strings.Repeat("-", 300)+"\"} {#if paid}x{/if} ", 2000)
+
+	tagged := strings.Repeat("{store}  {#if paid}\n{/if} {store}\n\t{#each items}{name} {/each}  \n{paid}{#if paid} {/if}  {store}\n", 3000)
+
+	boundary := strings.Repeat("x", 32<<10-9) + "\n{#if paid}\n{/if} {store}\nend"
+
+	for _, text := range []string{"", "plain", document, line, spaced, tagged, boundary} {
+		expect, err := curly.Format(text, formatter, receipt)
+		require.NoError(t, err)
+
+		for _, src := range []io.Reader{strings.NewReader(text), iotest.HalfReader(strings.NewReader(text)), iotest.OneByteReader(strings.NewReader(text))} {
+			var out strings.Builder
+			require.NoError(t, curly.FormatStream(&out, src, formatter, receipt))
+			require.Equal(t, expect, out.String())
+		}
+	}
+
+	// Errors are located in the whole stream
+	for _, text := range []string{
+		document + "Total: {total}",
+		document[:len(document)/2] + "{#if paid}{total}{/if}",
+		line + "{total}",
+		line + "\n{store} {total}",
+		document + "{store",
+		document + "{#if paid}",
+		document + "{store|super()}",
+	} {
+		_, expect := curly.Format(text, formatter, receipt)
+		require.Error(t, expect)
+
+		var out strings.Builder
+		err := curly.FormatStream(&out, iotest.HalfReader(strings.NewReader(text)), formatter, receipt)
+		require.Equal(t, expect, err)
+	}
+
+	err := curly.FormatStream(io.Discard, iotest.ErrReader(io.ErrClosedPipe), formatter, receipt)
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+
+	// A line of placeholders is rendered a part at a time
+	var out strings.Builder
+	err = curly.FormatStream(&out, io.MultiReader(strings.NewReader(spaced), iotest.ErrReader(io.ErrClosedPipe)), formatter, receipt)
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	require.NotEmpty(t, out.String())
+}
+
+func TestFormatStreamPartials(t *testing.T) {
+	loader := &countingLoader{loader: curly.NewMapLoader(map[string]string{
+		"header": "== {store} ==\n{>footer}",
+		"footer": "-- {id} --\n",
+	})}
+	engine := curly.NewEngine(curly.WithPartials(loader))
+	data := curly.NewMapFormatter(map[string]any{"store": "TOKO ABC", "id": 7})
+
+	text := strings.Repeat("{>header}Struk {store|left(20)}\n", 5000)
+	expect, err := engine.Format(text, data)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"header": 1, "footer": 1}, loader.loads)
+	loader.loads = map[string]int{}
+
+	var out strings.Builder
+	require.NoError(t, engine.FormatStream(&out, strings.NewReader(text), data))
+	require.Equal(t, expect, out.String())
+	require.Equal(t, map[string]int{"header": 1, "footer": 1}, loader.loads)
+}
+
+// countingLoader counts the loads of each partial.
+type countingLoader struct {
+	loader curly.PartialLoader
+	loads  map[string]int
+}
+
+func (l *countingLoader) Load(name string) (string, error) {
+	if l.loads == nil {
+		l.loads = map[string]int{}
+	}
+	l.loads[name]++
+	return l.loader.Load(name)
+}
+
+func TestFormatStreamLookups(t *testing.T) {
+	customers := &batchFormatter{names: map[string]string{"customer.c001": "John Doe", "customer.c002": "Jane Doe"}}
+
+	var sb strings.Builder
+	for i := 0; i < 5000; i++ {
+		sb.WriteString("{customer.c001|left(10)} {customer.c002}\n")
+	}
+	sb.WriteString("{customer.c001}")
+	text := sb.String()
+
+	var out strings.Builder
+	require.NoError(t, curly.FormatStream(&out, strings.NewReader(text), customers))
+	require.Equal(t, strings.Repeat("John Doe   Jane Doe\n", 5000)+"John Doe", out.String())
+	require.Equal(t, [][]string{{"customer.c001", "customer.c002"}}, customers.batches)
+	require.Zero(t, customers.values)
+
+	slow := &slowFormatter{delay: 20 * time.Millisecond}
+	engine := curly.NewEngine(curly.WithParallelLookups(2))
+	out.Reset()
+	require.NoError(t, engine.FormatStream(&out, strings.NewReader("{a} {b} {c} {d} {a}"), slow))
+	require.Equal(t, "a b c d a", out.String())
+	require.Equal(t, int32(4), slow.lookups.Load())
+	require.Equal(t, int32(2), slow.busiest.Load())
+}
@@ -0,0 +1,221 @@
+package curly
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NewStructFormatter creates a new StructFormatter resolving identifiers in the given value, usually a
+// struct or a pointer to a struct.
+func NewStructFormatter(v any) *StructFormatter {
+	return &StructFormatter{
+		value: reflect.ValueOf(v),
+	}
+}
+
+// StructFormatter formats values based on the fields of a struct. The segments of a dotted identifier
+// such as customer.name or items.0.price select a struct field, a map key or a slice index in turn.
+// A field is named by its curly tag, its json tag or else its name, and the fields of embedded structs
+// are promoted. Names and keys are matched regardless of case; a segment matching several map keys that
+// differ only in case, and none of them exactly, does not resolve.
+type StructFormatter struct {
+	value reflect.Value
+}
+
+// Valid checks if the identifier resolves to a value of the struct.
+func (f *StructFormatter) Valid(identifier string) bool {
+	_, _, keys, ok := resolvePath(f.value, identifier, true)
+	return ok && keys == nil
+}
+
+// Value returns the value the identifier resolves to in the struct.
+func (f *StructFormatter) Value(identifier string) (any, error) {
+	v, missing, keys, ok := resolvePath(f.value, identifier, true)
+	if ok && keys != nil {
+		return nil, ambiguityError(identifier, keys)
+	}
+	if !ok {
+		return nil, pathError(strings.ToLower(identifier), missing)
+	}
+	return valueOf(v), nil
+}
+
+// resolvePath walks the segments of the dotted path through structs, maps, slices, arrays, pointers
+// and interfaces. It returns the resolved value, or the segment that could not be resolved. A key
+// differing from the segment in case only matches when fold is true, and an exact key comes first;
+// when the segment matches several such keys, it resolves to none of them and the keys are returned.
+func resolvePath(v reflect.Value, path string, fold bool) (reflect.Value, string, []string, bool) {
+	for _, name := range strings.Split(path, ".") {
+		v = indirect(v)
+		if !v.IsValid() {
+			return reflect.Value{}, name, nil, false
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			index, ok := structFields(v.Type())[strings.ToLower(name)]
+			if !ok {
+				return reflect.Value{}, name, nil, false
+			}
+			field, err := v.FieldByIndexErr(index)
+			if err != nil {
+				return reflect.Value{}, name, nil, false
+			}
+			v = field
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, name, nil, false
+			}
+			if val := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())); val.IsValid() {
+				v = val
+				continue
+			}
+			var keys []string
+			var found reflect.Value
+			iter := v.MapRange()
+			for fold && iter.Next() {
+				if strings.EqualFold(iter.Key().String(), name) {
+					keys = append(keys, iter.Key().String())
+					found = iter.Value()
+				}
+			}
+			if len(keys) > 1 {
+				sort.Strings(keys)
+				return reflect.Value{}, name, keys, true
+			}
+			if len(keys) == 0 {
+				return reflect.Value{}, name, nil, false
+			}
+			v = found
+		case reflect.Slice, reflect.Array:
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= v.Len() {
+				return reflect.Value{}, name, nil, false
+			}
+			v = v.Index(i)
+		default:
+			return reflect.Value{}, name, nil, false
+		}
+	}
+	return v, "", nil, true
+}
+
+// pathError returns the error of an identifier that does not resolve, naming the missing segment of a
+// dotted identifier.
+func pathError(identifier string, missing string) error {
+	if !strings.Contains(identifier, ".") {
+		return fmt.Errorf("invalid identifier: \"%s\"", identifier)
+	}
+	return fmt.Errorf("invalid identifier: \"%s\": missing segment \"%s\"", identifier, missing)
+}
+
+// ambiguityError returns the error of an identifier matching several keys that differ only in case.
+func ambiguityError(identifier string, keys []string) error {
+	return fmt.Errorf("ambiguous identifier: \"%s\" matches keys \"%s\"", identifier, strings.Join(keys, "\", \""))
+}
+
+// indirect dereferences pointers and interfaces. It returns the zero Value for a nil pointer or interface.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// valueOf returns the value held by v, dereferencing pointers. A nil pointer or interface gives nil.
+func valueOf(v reflect.Value) any {
+	v = indirect(v)
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// fieldCache holds the field indexes of the struct types already seen, keyed by reflect.Type.
+var fieldCache sync.Map
+
+// structFields returns the exported fields of the struct type, including the promoted fields of
+// embedded structs, indexed by their lowercase name.
+func structFields(t reflect.Type) map[string][]int {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.(map[string][]int)
+	}
+	fields := collectFields(t)
+	actual, _ := fieldCache.LoadOrStore(t, fields)
+	return actual.(map[string][]int)
+}
+
+// collectFields indexes the fields of the struct type level by level, so that, as in Go, a field hides
+// the promoted fields of the same name found deeper in embedded structs.
+func collectFields(t reflect.Type) map[string][]int {
+	type level struct {
+		typ   reflect.Type
+		index []int
+	}
+	fields := map[string][]int{}
+	visited := map[reflect.Type]bool{}
+	current := []level{{typ: t}}
+	for len(current) > 0 {
+		var next []level
+		found := map[string][]int{}
+		for _, l := range current {
+			if visited[l.typ] {
+				continue
+			}
+			visited[l.typ] = true
+			for i := 0; i < l.typ.NumField(); i++ {
+				field := l.typ.Field(i)
+				name, tagged := fieldName(field)
+				if name == "-" {
+					continue
+				}
+				index := append(l.index[:len(l.index):len(l.index)], i)
+				if field.Anonymous && !tagged {
+					ft := field.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						next = append(next, level{typ: ft, index: index})
+						continue
+					}
+				}
+				if !field.IsExported() {
+					continue
+				}
+				if _, ok := found[name]; !ok {
+					found[name] = index
+				}
+			}
+		}
+		for name, index := range found {
+			if _, ok := fields[name]; !ok {
+				fields[name] = index
+			}
+		}
+		current = next
+	}
+	return fields
+}
+
+// fieldName returns the lowercase name of the struct field from its curly tag, its json tag or its
+// name, and whether the name comes from a tag.
+func fieldName(field reflect.StructField) (string, bool) {
+	for _, key := range []string{"curly", "json"} {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name != "" {
+			return strings.ToLower(name), true
+		}
+	}
+	return strings.ToLower(field.Name), false
+}
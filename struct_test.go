@@ -0,0 +1,97 @@
+package curly_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ceebydith/curly"
+	"github.com/stretchr/testify/require"
+)
+
+type testAudit struct {
+	ID        string `json:"id"`
+	CreatedBy string `json:"created_by"`
+}
+
+type testCustomer struct {
+	Name    string
+	Msisdn  string `curly:"phone" json:"msisdn"`
+	Address *string
+}
+
+type testItem struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+type testTransaction struct {
+	testAudit
+	ID       int64 `curly:"trxid"`
+	Customer *testCustomer
+	PLN      struct {
+		IDPel   string `json:"idpel"`
+		Tagihan int    `curly:"tagihan"`
+	} `json:"pln"`
+	Items  []testItem `json:"items"`
+	Extra  map[string]any
+	Secret string `curly:"-"`
+	note   string
+}
+
+func TestStructFormatter(t *testing.T) {
+	trx := testTransaction{
+		testAudit: testAudit{ID: "AUD-1", CreatedBy: "system"},
+		ID:        2189566,
+		Customer:  &testCustomer{Name: "John Doe", Msisdn: "081234567890"},
+		Items:     []testItem{{Name: "Token 20K", Price: 20000}, {Name: "Admin", Price: 2500}},
+		Extra:     map[string]any{"Channel": "mobile", "tags": []any{"promo", "new"}},
+		Secret:    "p455w0rd",
+		note:      "hidden",
+	}
+	trx.PLN.IDPel = "133312626789"
+	trx.PLN.Tagihan = 20075
+
+	testStruct := formatterTester{
+		formatter: curly.NewStructFormatter(&trx),
+		scenarios: []formatterScenarioTest{
+			{"trxid", true, int64(2189566), nil},
+			{"id", true, "AUD-1", nil},
+			{"created_by", true, "system", nil},
+			{"customer.name", true, "John Doe", nil},
+			{"Customer.Phone", true, "081234567890", nil},
+			{"customer.address", true, nil, nil},
+			{"pln.idpel", true, "133312626789", nil},
+			{"pln.tagihan", true, 20075, nil},
+			{"items.0.price", true, float64(20000), nil},
+			{"items.1.name", true, "Admin", nil},
+			{"extra.channel", true, "mobile", nil},
+			{"extra.tags.1", true, "new", nil},
+			{"items.2.price", false, nil, errors.New("invalid identifier: \"items.2.price\": missing segment \"2\"")},
+			{"customer.email", false, nil, errors.New("invalid identifier: \"customer.email\": missing segment \"email\"")},
+			{"secret", false, nil, errors.New("invalid identifier: \"secret\"")},
+			{"note", false, nil, errors.New("invalid identifier: \"note\"")},
+			{"pln.tagihan.amount", false, nil, errors.New("invalid identifier: \"pln.tagihan.amount\": missing segment \"amount\"")},
+		},
+	}
+
+	t.Run("TestStructFormatter", testStruct.Test)
+
+	text, err := curly.Format("TRX {trxid} {customer.name|left(10)} {pln.tagihan|money(,)} {items.0.name}", curly.NewStructFormatter(trx))
+	require.NoError(t, err)
+	require.Equal(t, "TRX 2189566 John Doe   20.075 Token 20K", text)
+
+	_, err = curly.Format("{customer.name}", curly.NewStructFormatter(testTransaction{}))
+	require.ErrorIs(t, err, curly.ErrUnknownIdentifier)
+}
+
+func BenchmarkStructFormatter(b *testing.B) {
+	formatter := curly.NewStructFormatter(&testTransaction{
+		Customer: &testCustomer{Name: "John Doe"},
+		Items:    []testItem{{Name: "Token 20K", Price: 20000}},
+	})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = formatter.Value("customer.name")
+		_, _ = formatter.Value("items.0.price")
+	}
+}
@@ -0,0 +1,725 @@
+package curly
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Template is a text whose placeholders have been parsed once, so that it can be formatted repeatedly
+// without scanning the text again.
+type Template struct {
+	engine *Engine
+	text   string
+	nodes  []node
+}
+
+// Compile parses the placeholders of the given text and returns a Template ready to be executed.
+func Compile(text string) (*Template, error) {
+	return defaultEngine.Compile(text)
+}
+
+// MustCompile is like Compile but panics if the text cannot be parsed.
+func MustCompile(text string) *Template {
+	t, err := Compile(text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// String returns the source text of the template.
+func (t *Template) String() string {
+	return t.text
+}
+
+// Execute applies a series of formatters to the template and returns the formatted string.
+func (t *Template) Execute(formatters ...Formatter) (string, error) {
+	text, _, err := t.ExecuteUnresolved(formatters...)
+	return text, err
+}
+
+// ExecuteContext is like Execute but reads the values of ContextFormatters with the given context, and
+// stops at the first lookup once the context is done.
+func (t *Template) ExecuteContext(ctx context.Context, formatters ...Formatter) (string, error) {
+	var sb strings.Builder
+	if _, err := t.execute(ctx, &sb, formatters); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// ExecuteUnresolved is like Execute but also returns the identifiers left unresolved, in order of
+// appearance, when the engine of the template does not use MissingError.
+func (t *Template) ExecuteUnresolved(formatters ...Formatter) (string, []string, error) {
+	var sb strings.Builder
+	unresolved, err := t.execute(context.Background(), &sb, formatters)
+	if err != nil {
+		return "", nil, err
+	}
+	return sb.String(), unresolved, nil
+}
+
+// ExecuteTo applies a series of formatters to the template and writes the formatted text to w.
+// On error, part of the output may already have been written.
+func (t *Template) ExecuteTo(w io.Writer, formatters ...Formatter) error {
+	bw := bufio.NewWriter(w)
+	if _, err := t.execute(context.Background(), bw, formatters); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Field describes a placeholder of a template.
+type Field struct {
+	Identifier string   // identifier of the placeholder
+	Fallbacks  []string // identifiers of the fallback chain, such as name in {nickname ?? name}
+	Modifier   string   // modifier applied to the value, without its leading default(...)
+	Raw        string   // source text of the placeholder
+	Offset     int      // byte offset of the placeholder in the template
+	Line       int      // line of the placeholder, starting at 1
+	Column     int      // column of the placeholder in characters, starting at 1
+	Partial    string   // name of the partial holding the placeholder, if any
+}
+
+// Fields returns the placeholders of the template and of its partials in order of appearance.
+func (t *Template) Fields() []Field {
+	return fields(t.nodes, t.text, "")
+}
+
+// fields describes the placeholders of the nodes parsed from text, and of their sections.
+func fields(nodes []node, text string, partial string) []Field {
+	var result []Field
+	for _, n := range nodes {
+		start := len(result)
+		switch n := n.(type) {
+		case *fieldNode:
+			result = append(result, n.field(text))
+		case *ifNode:
+			result = append(result, n.fields(text)...)
+		case *eachNode:
+			line, column := position(text, n.offset)
+			result = append(result, Field{Identifier: n.identifier, Raw: n.raw, Offset: n.offset, Line: line, Column: column})
+		case *partialNode:
+			result = append(result, n.fields(text)...)
+		}
+		for i := start; i < len(result); i++ {
+			result[i].Partial = partial
+		}
+
+		switch n := n.(type) {
+		case *partialNode:
+			result = append(result, fields(n.nodes, n.text, n.name)...)
+		case section:
+			for _, branch := range n.branches() {
+				result = append(result, fields(branch, text, partial)...)
+			}
+		}
+	}
+	return result
+}
+
+// Validate checks the template against the given formatters without reading any value, and returns
+// all the problems found. See Engine.Validate.
+func (t *Template) Validate(formatters ...Formatter) error {
+	s := &state{
+		engine:     t.engine,
+		text:       t.text,
+		formatters: t.engine.formattersFor(formatters),
+	}
+	return errors.Join(validate(t.nodes, s)...)
+}
+
+// validate checks the nodes and the nodes of their sections.
+func validate(nodes []node, s *state) []error {
+	var errs []error
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *fieldNode:
+			errs = append(errs, n.validate(s)...)
+		case *partialNode:
+			errs = append(errs, n.validate(s)...)
+		case *eachNode:
+			if s.formatter(n.identifier) == nil {
+				errs = append(errs, n.fail(s, ErrUnknownIdentifier, nil))
+			}
+
+			// The fields of the elements are only known when the template is executed
+			inner := *s
+			inner.formatters = append(s.formatters[:len(s.formatters):len(s.formatters)], anyFormatter{})
+			errs = append(errs, validate(n.body, &inner)...)
+			errs = append(errs, validate(n.otherwise, s)...)
+		case section:
+			for _, branch := range n.branches() {
+				errs = append(errs, validate(branch, s)...)
+			}
+		}
+	}
+	return errs
+}
+
+// execute renders every node of the template into w and returns the unresolved identifiers.
+func (t *Template) execute(ctx context.Context, w io.StringWriter, formatters []Formatter) ([]string, error) {
+	s := newState(ctx, t.engine, formatters)
+	s.text = t.text
+	s.prefetch(t.nodes)
+	for _, n := range t.nodes {
+		if err := n.render(w, s); err != nil {
+			return nil, err
+		}
+	}
+	return s.unresolved, nil
+}
+
+// state holds the data shared by the nodes during a single execution.
+type state struct {
+	ctx        context.Context
+	engine     *Engine
+	text       string
+	partial    string
+	formatters []Formatter
+	unresolved []string
+	shared     int                       // number of formatters, at the end of formatters, given to the execution
+	values     map[valueKey]lookupResult // values read from the formatters given to the execution
+}
+
+// fail returns a TemplateError of the given kind located at offset in the text being rendered.
+func (s *state) fail(kind error, offset int, text string, err error) *TemplateError {
+	e := newTemplateError(kind, s.text, offset, text, err)
+	e.Partial = s.partial
+	return e
+}
+
+// write writes the rendered text, escaped when the result must remain a template.
+func (s *state) write(w io.StringWriter, text string) error {
+	if s.engine.missing == MissingKeep {
+		text = escapeTemplate(text)
+	}
+	_, err := w.WriteString(text)
+	return err
+}
+
+// unresolve records an identifier left unresolved.
+func (s *state) unresolve(identifier string) {
+	for _, id := range s.unresolved {
+		if id == identifier {
+			return
+		}
+	}
+	s.unresolved = append(s.unresolved, identifier)
+}
+
+// snapshot replaces the formatters implementing Snapshotter by their snapshot.
+func snapshot(formatters []Formatter) []Formatter {
+	var result []Formatter
+	for i, f := range formatters {
+		if s, ok := f.(Snapshotter); ok {
+			if result == nil {
+				result = append([]Formatter{}, formatters...)
+			}
+			result[i] = s.Snapshot()
+		}
+	}
+	if result == nil {
+		return formatters
+	}
+	return result
+}
+
+// node is an element of a parsed template.
+type node interface {
+	render(w io.StringWriter, s *state) error
+}
+
+// walk calls fn for the nodes in order of appearance, descending into sections.
+func walk(nodes []node, fn func(n node)) {
+	for _, n := range nodes {
+		fn(n)
+		if s, ok := n.(section); ok {
+			for _, branch := range s.branches() {
+				walk(branch, fn)
+			}
+		}
+	}
+}
+
+// textNode is a literal part of a template, with escape sequences already resolved.
+type textNode struct {
+	text string
+}
+
+func (n *textNode) render(w io.StringWriter, s *state) error {
+	return s.write(w, n.text)
+}
+
+// fieldNode is a placeholder such as {identifier|modifier} or {identifier ?? fallback|modifier}.
+type fieldNode struct {
+	raw        string
+	identifier string
+	modifier   string
+	fallbacks  []fallback
+	offset     int
+}
+
+// fallback is an alternative of a placeholder, used when the identifiers before it are missing.
+type fallback struct {
+	identifier string
+	text       string
+	literal    bool
+}
+
+func (n *fieldNode) render(w io.StringWriter, s *state) error {
+	value, modifier, err := n.value(s)
+	if err != nil {
+		if s.engine.missing == MissingError || !errors.Is(err, ErrUnknownIdentifier) {
+			return err
+		}
+		s.unresolve(n.identifier)
+		if s.engine.missing == MissingKeep {
+			_, err = w.WriteString(n.raw)
+			return err
+		}
+		return nil
+	}
+
+	// Apply the modifier if present
+	value, err = s.engine.execModifier(value, modifier)
+	if err != nil {
+		return n.fail(s, errorKind(err, ErrInvalidModifier), err)
+	}
+
+	return s.write(w, fmt.Sprintf("%v", value))
+}
+
+// value resolves the identifier of the placeholder or else its first available fallback, and returns
+// the value with the part of the modifier left to apply.
+func (n *fieldNode) value(s *state) (any, string, error) {
+	value, modifier, found, err := s.lookup(n.identifier, n.modifier)
+	if err != nil {
+		return nil, "", n.fail(s, errorKind(err, ErrInvalidValue), err)
+	}
+	if found && (n.fallbacks == nil || !s.engine.emptyAsMissing || !isEmpty(value)) {
+		return value, modifier, nil
+	}
+	for _, fb := range n.fallbacks {
+		if fb.literal {
+			// A later stage may still provide the value
+			if s.engine.missing == MissingKeep {
+				break
+			}
+			return fb.text, n.modifier, nil
+		}
+		value, modifier, found, err = s.lookup(fb.identifier, n.modifier)
+		if err != nil {
+			return nil, "", n.fail(s, errorKind(err, ErrInvalidValue), err)
+		}
+		if found && (!s.engine.emptyAsMissing || !isEmpty(value)) {
+			return value, modifier, nil
+		}
+	}
+	return nil, "", n.fail(s, ErrUnknownIdentifier, nil)
+}
+
+// formatter returns the first formatter accepting the identifier, or nil.
+func (s *state) formatter(identifier string) Formatter {
+	for _, f := range s.formatters {
+		if f.Valid(identifier) {
+			return f
+		}
+	}
+	return nil
+}
+
+// lookup reads the identifier from the first formatter accepting it, letting the formatter interpret
+// the leading part of the modifier. It reports false when no formatter accepts the identifier.
+func (s *state) lookup(identifier string, modifier string) (value any, rest string, found bool, err error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, "", false, err
+	}
+	index := -1
+	for i, f := range s.formatters {
+		if f.Valid(identifier) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, "", false, nil
+	}
+	formatter := s.formatters[index]
+
+	// Get the value from the formatter, which may interpret the leading part of the modifier
+	if f, ok := asModifier(formatter, identifier); ok && modifier != "" {
+		value, rest, err = f.ValueModifier(identifier, modifier)
+		return value, rest, true, err
+	}
+
+	// The formatters given to the execution are read once per identifier
+	key := valueKey{index: index - len(s.formatters) + s.shared, identifier: identifier}
+	if s.values == nil || key.index < 0 {
+		value, err = s.value(formatter, identifier)
+		return value, modifier, true, err
+	}
+	r, ok := s.values[key]
+	if !ok {
+		r.value, r.err = s.value(formatter, identifier)
+		s.values[key] = r
+	}
+	return r.value, modifier, true, r.err
+}
+
+// value reads the identifier from the formatter, with the context of the execution when the formatter
+// accepts one.
+func (s *state) value(formatter Formatter, identifier string) (any, error) {
+	return valueContext(s.ctx, formatter, identifier)
+}
+
+// validate checks that a formatter provides the identifier or one of its fallbacks, and that the
+// modifiers accept the modifier.
+func (n *fieldNode) validate(s *state) []error {
+	var errs []error
+	identifier, formatter := n.identifier, s.formatter(n.identifier)
+	literal := false
+	for _, fb := range n.fallbacks {
+		if formatter != nil {
+			break
+		}
+		if fb.literal {
+			literal = true
+			break
+		}
+		identifier, formatter = fb.identifier, s.formatter(fb.identifier)
+	}
+	if formatter == nil && !literal {
+		errs = append(errs, n.fail(s, ErrUnknownIdentifier, nil))
+	}
+
+	// The formatter may interpret the leading part of the modifier
+	modifier := n.modifier
+	if f, ok := formatter.(ModifierValidator); ok && modifier != "" {
+		modifier = f.ValidModifier(identifier, modifier)
+	} else if _, ok := formatter.(ModifierFormatter); ok && modifier != "" {
+		return errs
+	}
+	if err := s.engine.checkModifier(modifier); err != nil {
+		errs = append(errs, n.fail(s, errorKind(err, ErrInvalidModifier), err))
+	}
+	return errs
+}
+
+// field describes the placeholder located in the source text of its template.
+func (n *fieldNode) field(text string) Field {
+	line, column := position(text, n.offset)
+	f := Field{
+		Identifier: n.identifier,
+		Modifier:   n.modifier,
+		Raw:        n.raw,
+		Offset:     n.offset,
+		Line:       line,
+		Column:     column,
+	}
+	for _, fb := range n.fallbacks {
+		if !fb.literal {
+			f.Fallbacks = append(f.Fallbacks, fb.identifier)
+		}
+	}
+	return f
+}
+
+// fail returns a TemplateError of the given kind located at the placeholder.
+func (n *fieldNode) fail(s *state, kind error, err error) error {
+	e := s.fail(kind, n.offset, n.raw, err)
+	e.Identifier = n.identifier
+	e.Modifier = n.modifier
+	return e
+}
+
+// parseTemplate splits the text into literal, placeholder and section nodes.
+// A backslash escapes "\", "{" and "}"; any other brace outside a placeholder is an error.
+func parseTemplate(text string) ([]node, error) {
+	p := &templateParser{text: text}
+	nodes, tag, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if tag != nil {
+		return nil, p.fail(tag, fmt.Errorf("unexpected %s", tag.raw))
+	}
+	return nodes, nil
+}
+
+// templateParser reads the nodes of a template, descending into its sections.
+type templateParser struct {
+	text string
+	pos  int
+}
+
+// parseNodes reads nodes until the end of the text, or until a tag that does not open a section, such
+// as {#else} or {/if}, which is returned to the section being read.
+func (p *templateParser) parseNodes() ([]node, *blockTag, error) {
+	text := p.text
+	var nodes []node
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			nodes = append(nodes, &textNode{text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for p.pos < len(text) {
+		i := p.pos
+		switch c := text[i]; {
+		case c == '\\' && i+1 < len(text) && isEscapable(text[i+1]):
+			literal.WriteByte(text[i+1])
+			p.pos += 2
+		case c == '{':
+			if partial, n, ok := scanPartial(text[i:]); ok {
+				flush()
+				partial.raw, partial.offset = text[i:i+n], i
+				nodes = append(nodes, partial)
+				p.pos += n
+				continue
+			}
+			if tag, n, ok := scanTag(text[i:]); ok {
+				tag.raw = text[i : i+n]
+				tag.offset = i
+				tag.start, tag.end = i, i+n
+
+				// A tag alone on its line does not leave an empty line behind
+				if start, end, ok := standalone(text, i, i+n); ok {
+					kept := literal.String()
+					literal.Reset()
+					literal.WriteString(kept[:len(kept)-(i-start)])
+					tag.start, tag.end = start, end
+				}
+				p.pos = tag.end
+				flush()
+				if !tag.opening() {
+					return nodes, tag, nil
+				}
+				n, err := p.parseSection(tag)
+				if err != nil {
+					return nil, nil, err
+				}
+				nodes = append(nodes, n)
+				continue
+			}
+
+			var fallbacks []fallback
+			identifier, modifier, n, ok := scanPlaceholder(text[i:])
+			if ok {
+				fallbacks, modifier = splitDefault(modifier)
+			} else {
+				identifier, fallbacks, modifier, n, ok = scanFallback(text[i:])
+			}
+			if !ok {
+				return nil, nil, newTemplateError(ErrInvalidExpression, text, i, fragment(text, i), nil)
+			}
+			flush()
+			nodes = append(nodes, &fieldNode{
+				raw:        text[i : i+n],
+				identifier: identifier,
+				modifier:   modifier,
+				fallbacks:  fallbacks,
+				offset:     i,
+			})
+			p.pos += n
+		case c == '}':
+			return nil, nil, newTemplateError(ErrInvalidExpression, text, i, "}", nil)
+		default:
+			literal.WriteByte(c)
+			p.pos++
+		}
+	}
+	flush()
+	return nodes, nil, nil
+}
+
+// fail returns a TemplateError located at the tag.
+func (p *templateParser) fail(tag *blockTag, err error) error {
+	return newTemplateError(ErrInvalidExpression, p.text, tag.offset, tag.raw, err)
+}
+
+// scanPlaceholder reads a placeholder such as {identifier} or {identifier|modifier} at the start of s.
+// It returns the identifier, the trimmed modifier and the length of the placeholder.
+func scanPlaceholder(s string) (identifier string, modifier string, n int, ok bool) {
+	if len(s) == 0 || s[0] != '{' {
+		return "", "", 0, false
+	}
+	start := skipSpace(s, 1)
+	i := scanIdentifier(s, start)
+	if i == start {
+		return "", "", 0, false
+	}
+	identifier = s[start:i]
+	modifier, n, ok = scanModifier(s, skipSpace(s, i))
+	if !ok {
+		return "", "", 0, false
+	}
+	return identifier, modifier, n, true
+}
+
+// scanFallback reads a placeholder with a fallback chain such as {nickname ?? name ?? "Customer"} at the
+// start of s. It returns the identifier, its fallbacks, the trimmed modifier following the chain, which
+// starts with "|", and the length of the placeholder.
+func scanFallback(s string) (identifier string, fallbacks []fallback, modifier string, n int, ok bool) {
+	if len(s) == 0 || s[0] != '{' {
+		return "", nil, "", 0, false
+	}
+	start := skipSpace(s, 1)
+	i := scanIdentifier(s, start)
+	if i == start {
+		return "", nil, "", 0, false
+	}
+	identifier = s[start:i]
+	i = skipSpace(s, i)
+	for strings.HasPrefix(s[i:], "??") {
+		var fb fallback
+		fb, i, ok = scanAlternative(s, skipSpace(s, i+2))
+		if !ok {
+			return "", nil, "", 0, false
+		}
+		fallbacks = append(fallbacks, fb)
+		i = skipSpace(s, i)
+	}
+	if len(fallbacks) == 0 || i >= len(s) || (s[i] != '}' && s[i] != '|') {
+		return "", nil, "", 0, false
+	}
+	modifier, n, ok = scanModifier(s, i)
+	if !ok {
+		return "", nil, "", 0, false
+	}
+	return identifier, fallbacks, modifier, n, true
+}
+
+// scanAlternative reads the alternative of a fallback chain at i and returns it with its end. An
+// alternative is a text between double quotes, an identifier, or else the non-empty literal text up to
+// the next "??", "|" or closing brace.
+func scanAlternative(s string, i int) (fallback, int, bool) {
+	var sb strings.Builder
+	if i < len(s) && s[i] == '"' {
+		for j := i + 1; j < len(s); j++ {
+			switch c := s[j]; {
+			case c == '\\' && j+1 < len(s) && (isEscapable(s[j+1]) || s[j+1] == '"'):
+				sb.WriteByte(s[j+1])
+				j++
+			case c == '"':
+				return fallback{text: sb.String(), literal: true}, j + 1, true
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return fallback{}, i, false
+	}
+
+	j := i
+	for ; j < len(s); j++ {
+		c := s[j]
+		if c == '\\' && j+1 < len(s) && isEscapable(s[j+1]) {
+			sb.WriteByte(s[j+1])
+			j++
+			continue
+		}
+		if c == '{' || c == '}' || c == '|' || strings.HasPrefix(s[j:], "??") {
+			break
+		}
+		sb.WriteByte(c)
+	}
+	if j >= len(s) || s[j] == '{' {
+		return fallback{}, i, false
+	}
+	text := strings.TrimSpace(sb.String())
+	if text == "" {
+		return fallback{}, i, false
+	}
+	if scanIdentifier(text, 0) == len(text) {
+		return fallback{identifier: text}, j, true
+	}
+	return fallback{text: text, literal: true}, j, true
+}
+
+// scanIdentifier returns the end of the identifier starting at i, or i when there is none.
+// An identifier is a letter followed by runs of letters, digits and "_" separated by a single ".",
+// optionally followed by "@" and a time zone name such as Asia/Jakarta.
+func scanIdentifier(s string, i int) int {
+	if i >= len(s) || !isLetter(s[i]) {
+		return i
+	}
+	for i < len(s) && (isAlnum(s[i]) || s[i] == '_') {
+		i++
+	}
+	for i+1 < len(s) && s[i] == '.' && (isAlnum(s[i+1]) || s[i+1] == '_') {
+		i++
+		for i < len(s) && (isAlnum(s[i]) || s[i] == '_') {
+			i++
+		}
+	}
+
+	if i+1 < len(s) && s[i] == '@' && isLetter(s[i+1]) {
+		i++
+		for i < len(s) && (isAlnum(s[i]) || s[i] == '_' || (strings.IndexByte("/-+", s[i]) >= 0 && i+1 < len(s) && isLetter(s[i+1]))) {
+			i++
+		}
+	}
+	return i
+}
+
+// scanModifier reads the optional modifier at i, which starts with an operator and runs until the
+// closing brace. It returns the trimmed modifier and the end of the placeholder.
+func scanModifier(s string, i int) (string, int, bool) {
+	if i >= len(s) {
+		return "", 0, false
+	}
+	if s[i] == '}' {
+		return "", i + 1, true
+	}
+	if !strings.ContainsRune(`*/+-:|`, rune(s[i])) {
+		return "", 0, false
+	}
+	var sb strings.Builder
+	for j := i; j < len(s); j++ {
+		switch c := s[j]; {
+		case c == '\\' && j+1 < len(s) && isEscapable(s[j+1]):
+			sb.WriteByte(s[j+1])
+			j++
+		case c == '}':
+			if j-i < 2 {
+				return "", 0, false
+			}
+			return strings.TrimSpace(sb.String()), j + 1, true
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
+
+// regexDefault matches a modifier starting with default(text) and the rest of the modifier.
+var regexDefault = regexp.MustCompile(`(?i)^\|\s*default\((.*?)\)\s*(\|.*)?$`)
+
+// splitDefault turns the leading default(text) of the modifier into a fallback and returns the rest
+// of the modifier.
+func splitDefault(modifier string) ([]fallback, string) {
+	match := regexDefault.FindStringSubmatch(modifier)
+	if match == nil {
+		return nil, modifier
+	}
+	return []fallback{{text: match[1], literal: true}}, strings.TrimSpace(match[2])
+}
+
+// fragment returns the text at offset up to the next closing brace or the end of the line.
+func fragment(text string, offset int) string {
+	end := len(text)
+	if i := strings.IndexAny(text[offset:], "}\n"); i >= 0 {
+		end = offset + i
+		if text[end] == '}' {
+			end++
+		}
+	}
+	return text[offset:end]
+}
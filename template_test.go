@@ -0,0 +1,430 @@
+package curly_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ceebydith/curly"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplate(t *testing.T) {
+	mapFormatter := curly.NewMapFormatter(map[string]any{
+		"appname":   "curly",
+		"index":     0,
+		"file":      "text.txt",
+		"amount":    1500000,
+		"APP_NAME":  "curly",
+		"app__name": "curly",
+	})
+
+	testTemplate := templateTester{
+		formatters: []curly.Formatter{mapFormatter},
+		scenarios: []templateScenarioTest{
+			{"plain text", "plain text", nil},
+			{"Application {appname}", "Application curly", nil},
+			{"{ appname }-{appname}", "curly-curly", nil},
+			{"file {file|remove(.txt)}, position {index+1}", "file text, position 1", nil},
+			{"Rp. {amount|money(,)|right(12)}", "Rp.    1.500.000", nil},
+			{"100% \\{appname\\} \\\\{appname}", "100% {appname} \\curly", nil},
+			{"C:\\logs\\\\{appname}.log", "C:\\logs\\curly.log", nil},
+			{"{file|post(\\})}", "text.txt}", nil},
+			{"{APP_NAME}/{app__name}", "curly/curly", nil},
+			{"{workdir}", "", &curly.TemplateError{
+				Kind: curly.ErrUnknownIdentifier, Text: "{workdir}", Offset: 0, Line: 1, Column: 1, Identifier: "workdir",
+			}},
+			{"{{appname}}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{{appname}", Offset: 0, Line: 1, Column: 1,
+			}},
+			{"{appname} }", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "}", Offset: 10, Line: 1, Column: 11,
+			}},
+			{"{appname|super()}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidModifier, Text: "{appname|super()}", Offset: 0, Line: 1, Column: 1, Identifier: "appname", Modifier: "|super()",
+				Err: &curly.ModifierError{Kind: curly.ErrInvalidModifier, Expression: "|super()"},
+			}},
+			{"Name : {appname}\nIndex: {index/0}", "", &curly.TemplateError{
+				Kind: curly.ErrDivisionByZero, Text: "{index/0}", Offset: 24, Line: 2, Column: 8, Identifier: "index", Modifier: "/0",
+				Err: &curly.ModifierError{Kind: curly.ErrDivisionByZero, Expression: "0/0"},
+			}},
+		},
+	}
+
+	t.Run("TestTemplate", testTemplate.Test)
+}
+
+func TestTemplateFallback(t *testing.T) {
+	testFallback := templateTester{
+		formatters: []curly.Formatter{curly.NewMapFormatter(map[string]any{
+			"name":  "John Doe",
+			"info":  "",
+			"total": 20075,
+		})},
+		scenarios: []templateScenarioTest{
+			{"Info: {detail ?? -}", "Info: -", nil},
+			{"Info: {detail|default(N/A)}", "Info: N/A", nil},
+			{"Info: {info ?? -}", "Info: ", nil},
+			{"Dear {nickname ?? name ?? \"Customer\"}", "Dear John Doe", nil},
+			{"Dear {nickname ?? alias ?? \"Customer\"}", "Dear Customer", nil},
+			{"Dear {nickname??\"Mr. \\\"X\\\"\"}", "Dear Mr. \"X\"", nil},
+			{"{nickname ?? name|left(10)}|", "John Doe  |", nil},
+			{"{detail ?? N/A|right(5)}", "  N/A", nil},
+			{"[{detail ?? \"\"}]", "[]", nil},
+			{"{detail|default()|pre(x)}", "x", nil},
+			{"{fee ?? 0|money(,)} {total ?? 0|money(,)}", "0 20.075", nil},
+			{"{nickname ?? \\{none\\}}", "{none}", nil},
+			{"{nickname ?? alias}", "", &curly.TemplateError{
+				Kind: curly.ErrUnknownIdentifier, Text: "{nickname ?? alias}", Offset: 0, Line: 1, Column: 1, Identifier: "nickname",
+			}},
+			{"{nickname ?? \"Customer}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{nickname ?? \"Customer}", Offset: 0, Line: 1, Column: 1,
+			}},
+			{"{nickname ??}", "", &curly.TemplateError{
+				Kind: curly.ErrInvalidExpression, Text: "{nickname ??}", Offset: 0, Line: 1, Column: 1,
+			}},
+		},
+	}
+
+	t.Run("TestTemplateFallback", testFallback.Test)
+
+	engine := curly.NewEngine(curly.WithEmptyAsMissing())
+	data := curly.NewMapFormatter(map[string]any{"info": "", "note": nil, "name": "John Doe"})
+	text, err := engine.Format("{info ?? -}/{note|default(-)}/{info ?? note ?? name}/{info}", data)
+	require.NoError(t, err)
+	require.Equal(t, "-/-/John Doe/", text)
+}
+
+func TestTemplateFields(t *testing.T) {
+	fields, err := curly.Identifiers("Dear {nickname ?? name ?? \"Customer\"},\nTotal: {pln.total|money(,)|right(15)}\n{info|default(-)}")
+	require.NoError(t, err)
+	require.Equal(t, []curly.Field{
+		{Identifier: "nickname", Fallbacks: []string{"name"}, Raw: "{nickname ?? name ?? \"Customer\"}", Offset: 5, Line: 1, Column: 6},
+		{Identifier: "pln.total", Modifier: "|money(,)|right(15)", Raw: "{pln.total|money(,)|right(15)}", Offset: 46, Line: 2, Column: 8},
+		{Identifier: "info", Raw: "{info|default(-)}", Offset: 77, Line: 3, Column: 1},
+	}, fields)
+
+	require.Equal(t, fields, curly.MustCompile("Dear {nickname ?? name ?? \"Customer\"},\nTotal: {pln.total|money(,)|right(15)}\n{info|default(-)}").Fields())
+	require.Empty(t, curly.MustCompile("plain \\{text\\}").Fields())
+
+	_, err = curly.Identifiers("{name")
+	require.ErrorIs(t, err, curly.ErrInvalidExpression)
+}
+
+func TestTemplateValidate(t *testing.T) {
+	data := &countingFormatter{Formatter: curly.NewMapFormatter(map[string]any{"name": "John Doe", "amount": 150000, "file": "a.txt"})}
+	datetime := curly.NewDatetimeFormatter()
+
+	err := curly.Validate("{name|left(10)} {amount*2} {amount|money(,)|right(15)} {file|remove(.txt)|center(9)} {nickname ?? name} {info ?? -|pre(x)} {date-1d|fmt(yyyy/mm/dd)}", data, datetime)
+	require.NoError(t, err)
+
+	text := "Name  : {name|super(10)}\nTotal : {amount*(2}\nFee   : {amount/(1-1)}\nInfo  : {info}\nDate  : {date-1x}\nFile  : {file|remove(.txt)|upper()}\n{nickname ?? alias}"
+	err = curly.Validate(text, data, datetime)
+	require.Error(t, err)
+	require.Equal(t, 0, data.values)
+	require.ErrorIs(t, err, curly.ErrInvalidModifier)
+	require.ErrorIs(t, err, curly.ErrDivisionByZero)
+	require.ErrorIs(t, err, curly.ErrUnknownIdentifier)
+
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	require.Len(t, errs, 7)
+	for i, expect := range []struct {
+		kind error
+		line int
+	}{
+		{curly.ErrInvalidModifier, 1},
+		{curly.ErrInvalidModifier, 2},
+		{curly.ErrDivisionByZero, 3},
+		{curly.ErrUnknownIdentifier, 4},
+		{curly.ErrInvalidModifier, 5},
+		{curly.ErrInvalidModifier, 6},
+		{curly.ErrUnknownIdentifier, 7},
+	} {
+		var templateErr *curly.TemplateError
+		require.True(t, errors.As(errs[i], &templateErr), "#%d", i)
+		require.ErrorIs(t, templateErr, expect.kind, "#%d", i)
+		require.Equal(t, expect.line, templateErr.Line, "#%d", i)
+	}
+	require.EqualError(t, errs[5], "invalid modifier: \"{file|remove(.txt)|upper()}\" at line 6, column 9: invalid modifier: \"upper()\"")
+
+	// The modifiers of the engine are used
+	sandbox := curly.NewEngine(curly.WithModifiers(curly.NewFormatModifier()))
+	require.ErrorIs(t, sandbox.Validate("{amount*2}", data), curly.ErrInvalidModifier)
+	require.NoError(t, curly.MustCompile("{amount*2}").Validate(data))
+
+	_, err = curly.Format(text, data)
+	require.Error(t, err)
+	require.NotZero(t, data.values)
+}
+
+// countingFormatter counts the values read from its formatter.
+type countingFormatter struct {
+	curly.Formatter
+	values int
+}
+
+func (f *countingFormatter) Value(identifier string) (any, error) {
+	f.values++
+	return f.Formatter.Value(identifier)
+}
+
+func TestTemplateContext(t *testing.T) {
+	customers := &customerFormatter{names: map[string]string{"C001": "John Doe"}}
+	data := curly.NewMapFormatter(map[string]any{"trxid": "TRX-1", "customer.C001": "cached"})
+
+	text, err := curly.FormatContext(context.Background(), "{trxid}: {customer.c001|left(10)}|", customers, data)
+	require.NoError(t, err)
+	require.Equal(t, "TRX-1: John Doe  |", text)
+	require.Equal(t, 1, customers.lookups)
+
+	// Formatters without ValueContext are read with Value
+	text, err = curly.FormatContext(context.Background(), "{trxid}: {customer.c001}", data)
+	require.NoError(t, err)
+	require.Equal(t, "TRX-1: cached", text)
+
+	// The lookup follows the deadline of the context
+	customers.delay, customers.lookups = time.Second, 0
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = curly.FormatContext(ctx, "{customer.c001} {customer.c001} {trxid}", customers, data)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorIs(t, err, curly.ErrInvalidValue)
+	require.Equal(t, 1, customers.lookups)
+	require.EqualError(t, err, "invalid value: \"{customer.c001}\" at line 1, column 1: context deadline exceeded")
+
+	// Nothing is read once the context is done
+	customers.lookups = 0
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = curly.MustCompile("{trxid} {customer.c001}").ExecuteContext(canceled, customers, data)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, customers.lookups)
+
+	// Other functions read ContextFormatters with context.Background
+	customers.delay = 0
+	text, err = curly.Format("{customer.c001}", customers)
+	require.NoError(t, err)
+	require.Equal(t, "John Doe", text)
+}
+
+// customerFormatter reads the customer names as if from a database, which takes the given delay.
+type customerFormatter struct {
+	names   map[string]string
+	delay   time.Duration
+	lookups int
+}
+
+func (f *customerFormatter) Valid(identifier string) bool {
+	return strings.HasPrefix(strings.ToLower(identifier), "customer.")
+}
+
+func (f *customerFormatter) Value(identifier string) (any, error) {
+	return f.ValueContext(context.Background(), identifier)
+}
+
+func (f *customerFormatter) ValueContext(ctx context.Context, identifier string) (any, error) {
+	f.lookups++
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	name, ok := f.names[strings.ToUpper(identifier[len("customer."):])]
+	if !ok {
+		return nil, fmt.Errorf("%w: \"%s\"", curly.ErrUnknownIdentifier, identifier)
+	}
+	return name, nil
+}
+
+func TestTemplateLookups(t *testing.T) {
+	// Every identifier is read once per execution
+	data := &countingFormatter{Formatter: curly.NewMapFormatter(map[string]any{"yyyy": "2024", "app": "curly", "items": []any{"a", "b"}})}
+	text, err := curly.Format("{yyyy}/{app}-{yyyy}{#each items}.{app}{/each}.log", data)
+	require.NoError(t, err)
+	require.Equal(t, "2024/curly-2024.curly.curly.log", text)
+	require.Equal(t, 3, data.values)
+
+	// A BatchFormatter is read at once
+	customers := &batchFormatter{names: map[string]string{"customer.c001": "John Doe", "customer.c002": "Jane Doe"}}
+	text, err = curly.Format("{customer.c001}, {customer.c002}, {customer.c001|left(4)}{#if customer.c002 != \"\"}!{/if}", customers)
+	require.NoError(t, err)
+	require.Equal(t, "John Doe, Jane Doe, John!", text)
+	require.Equal(t, [][]string{{"customer.c001", "customer.c002"}}, customers.batches)
+	require.Equal(t, 0, customers.values)
+
+	// The identifiers left out of the batch are read one by one
+	customers.batches, customers.skip = nil, "customer.c002"
+	text, err = curly.Format("{customer.c001} {customer.c002} {customer.c003 ?? \"-\"}", customers)
+	require.NoError(t, err)
+	require.Equal(t, "John Doe Jane Doe -", text)
+	require.Equal(t, [][]string{{"customer.c001", "customer.c002"}}, customers.batches)
+	require.Equal(t, 1, customers.values)
+
+	customers.fail = errors.New("connection refused")
+	_, err = curly.Format("{customer.c001}", customers)
+	require.ErrorIs(t, err, curly.ErrInvalidValue)
+	require.EqualError(t, err, "invalid value: \"{customer.c001}\" at line 1, column 1: connection refused")
+
+	// Slow lookups run concurrently, up to the limit of the engine
+	slow := &slowFormatter{delay: 20 * time.Millisecond}
+	engine := curly.NewEngine(curly.WithParallelLookups(2))
+	text, err = engine.Format("{a} {b} {c} {d} {a}", slow)
+	require.NoError(t, err)
+	require.Equal(t, "a b c d a", text)
+	require.Equal(t, int32(4), slow.lookups.Load())
+	require.Equal(t, int32(2), slow.busiest.Load())
+}
+
+// batchFormatter reads the customer names as if with a single database query.
+type batchFormatter struct {
+	names   map[string]string
+	skip    string
+	fail    error
+	batches [][]string
+	values  int
+}
+
+func (f *batchFormatter) Valid(identifier string) bool {
+	_, ok := f.names[identifier]
+	return ok
+}
+
+func (f *batchFormatter) Value(identifier string) (any, error) {
+	f.values++
+	return f.names[identifier], nil
+}
+
+func (f *batchFormatter) Values(identifiers []string) (map[string]any, error) {
+	f.batches = append(f.batches, identifiers)
+	if f.fail != nil {
+		return nil, f.fail
+	}
+	values := map[string]any{}
+	for _, identifier := range identifiers {
+		if identifier != f.skip {
+			values[identifier] = f.names[identifier]
+		}
+	}
+	return values, nil
+}
+
+// slowFormatter returns every identifier as its value after the given delay, recording the largest
+// number of lookups running at once.
+type slowFormatter struct {
+	delay   time.Duration
+	lookups atomic.Int32
+	running atomic.Int32
+	busiest atomic.Int32
+}
+
+func (f *slowFormatter) Valid(identifier string) bool {
+	return true
+}
+
+func (f *slowFormatter) Value(identifier string) (any, error) {
+	f.lookups.Add(1)
+	running := f.running.Add(1)
+	defer f.running.Add(-1)
+	for busiest := f.busiest.Load(); running > busiest && !f.busiest.CompareAndSwap(busiest, running); busiest = f.busiest.Load() {
+	}
+	time.Sleep(f.delay)
+	return identifier, nil
+}
+
+func TestTemplateExecuteTo(t *testing.T) {
+	tmpl, err := curly.Compile("{name|pre(Hello, )|post(!)}")
+	require.NoError(t, err)
+
+	var sb strings.Builder
+	err = tmpl.ExecuteTo(&sb, curly.NewMapFormatter(map[string]any{"name": "John Doe"}))
+	require.NoError(t, err)
+	require.Equal(t, "Hello, John Doe!", sb.String())
+}
+
+func BenchmarkFormat(b *testing.B) {
+	formatter := curly.NewMapFormatter(benchmarkData)
+	for i := 0; i < b.N; i++ {
+		if _, err := curly.Format(benchmarkReceipt, formatter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTemplateExecute(b *testing.B) {
+	formatter := curly.NewMapFormatter(benchmarkData)
+	tmpl := curly.MustCompile(benchmarkReceipt)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tmpl.Execute(formatter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var benchmarkData = map[string]any{
+	"product":       "PLN PREPAID",
+	"denom":         20000,
+	"dest":          "133312626789",
+	"pln.nama":      "MBOK DARMI",
+	"pln.tarifdaya": "R1/2200VA",
+	"pln.kwh":       12.6,
+	"pln.tagihan":   18181,
+	"pln.ppj":       1819,
+	"pln.total":     20000,
+}
+
+var benchmarkReceipt = strings.Join([]string{
+	"====================================",
+	"         BUKTI PEMBAYARAN",
+	"{product|center(36)}",
+	"",
+	"Nominal        : {denom}",
+	"No. Pelanggan  : {dest}",
+	"Nama Pelanggan : {pln.nama}",
+	"Tarif/Daya     : {pln.tarifdaya}",
+	"KWH            : {pln.kwh} KWM",
+	"Tagihan        : Rp. {pln.tagihan|money(,)|right(15)}",
+	"PPJ            : Rp. {pln.ppj|money(,)|right(15)}",
+	"Total          : Rp. {pln.total|money(,)|right(15)}",
+	"",
+	"            TERIMAKASIH",
+	"====================================",
+}, "\n")
+
+type templateScenarioTest struct {
+	text         string
+	expectFormat string
+	expectError  error
+}
+
+type templateTester struct {
+	engine     *curly.Engine
+	formatters []curly.Formatter
+	scenarios  []templateScenarioTest
+}
+
+func (tester *templateTester) Test(t *testing.T) {
+	compile, format := curly.Compile, curly.Format
+	if tester.engine != nil {
+		compile, format = tester.engine.Compile, tester.engine.Format
+	}
+	for i, scenario := range tester.scenarios {
+		msg := fmt.Sprintf("#%d %s", i, scenario.text)
+
+		var result string
+		tmpl, err := compile(scenario.text)
+		if err == nil {
+			result, err = tmpl.Execute(tester.formatters...)
+		}
+		require.Equal(t, scenario.expectError, err, "Execute "+msg)
+		require.Equal(t, scenario.expectFormat, result, "Execute "+msg)
+
+		result, err = format(scenario.text, tester.formatters...)
+		require.Equal(t, scenario.expectError, err, "Format "+msg)
+		require.Equal(t, scenario.expectFormat, result, "Format "+msg)
+	}
+}